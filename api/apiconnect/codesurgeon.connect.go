@@ -52,6 +52,9 @@ const (
 	// GptServiceParseCodebaseProcedure is the fully-qualified name of the GptService's ParseCodebase
 	// RPC.
 	GptServiceParseCodebaseProcedure = "/codesurgeon.GptService/ParseCodebase"
+	// GptServiceApplyFileChangesProcedure is the fully-qualified name of the GptService's
+	// ApplyFileChanges RPC.
+	GptServiceApplyFileChangesProcedure = "/codesurgeon.GptService/ApplyFileChanges"
 )
 
 // These variables are the protoreflect.Descriptor objects for the RPCs defined in this package.
@@ -64,6 +67,7 @@ var (
 	gptServiceThinkThroughProblemMethodDescriptor    = gptServiceServiceDescriptor.Methods().ByName("ThinkThroughProblem")
 	gptServiceAddKnowledgeMethodDescriptor           = gptServiceServiceDescriptor.Methods().ByName("AddKnowledge")
 	gptServiceParseCodebaseMethodDescriptor          = gptServiceServiceDescriptor.Methods().ByName("ParseCodebase")
+	gptServiceApplyFileChangesMethodDescriptor       = gptServiceServiceDescriptor.Methods().ByName("ApplyFileChanges")
 )
 
 // GptServiceClient is a client for the codesurgeon.GptService service.
@@ -82,6 +86,8 @@ type GptServiceClient interface {
 	AddKnowledge(context.Context, *connect.Request[api.AddKnowledgeRequest]) (*connect.Response[api.AddKnowledgeResponse], error)
 	// ParseCodebase parses a file or directory
 	ParseCodebase(context.Context, *connect.Request[api.ParseCodebaseRequest]) (*connect.Response[api.ParseCodebaseResponse], error)
+	// ApplyFileChanges inserts or overwrites code fragments in go files, or previews the changes with dry_run
+	ApplyFileChanges(context.Context, *connect.Request[api.ApplyFileChangesRequest]) (*connect.Response[api.ApplyFileChangesResponse], error)
 }
 
 // NewGptServiceClient constructs a client for the codesurgeon.GptService service. By default, it
@@ -136,6 +142,12 @@ func NewGptServiceClient(httpClient connect.HTTPClient, baseURL string, opts ...
 			connect.WithSchema(gptServiceParseCodebaseMethodDescriptor),
 			connect.WithClientOptions(opts...),
 		),
+		applyFileChanges: connect.NewClient[api.ApplyFileChangesRequest, api.ApplyFileChangesResponse](
+			httpClient,
+			baseURL+GptServiceApplyFileChangesProcedure,
+			connect.WithSchema(gptServiceApplyFileChangesMethodDescriptor),
+			connect.WithClientOptions(opts...),
+		),
 	}
 }
 
@@ -148,6 +160,7 @@ type gptServiceClient struct {
 	thinkThroughProblem    *connect.Client[api.ThinkThroughProblemRequest, api.ThinkThroughProblemResponse]
 	addKnowledge           *connect.Client[api.AddKnowledgeRequest, api.AddKnowledgeResponse]
 	parseCodebase          *connect.Client[api.ParseCodebaseRequest, api.ParseCodebaseResponse]
+	applyFileChanges       *connect.Client[api.ApplyFileChangesRequest, api.ApplyFileChangesResponse]
 }
 
 // GetOpenAPI calls codesurgeon.GptService.GetOpenAPI.
@@ -185,6 +198,11 @@ func (c *gptServiceClient) ParseCodebase(ctx context.Context, req *connect.Reque
 	return c.parseCodebase.CallUnary(ctx, req)
 }
 
+// ApplyFileChanges calls codesurgeon.GptService.ApplyFileChanges.
+func (c *gptServiceClient) ApplyFileChanges(ctx context.Context, req *connect.Request[api.ApplyFileChangesRequest]) (*connect.Response[api.ApplyFileChangesResponse], error) {
+	return c.applyFileChanges.CallUnary(ctx, req)
+}
+
 // GptServiceHandler is an implementation of the codesurgeon.GptService service.
 type GptServiceHandler interface {
 	// GetOpenAPI retrieves the OpenAPI specification that can be used to on customGPT
@@ -201,6 +219,8 @@ type GptServiceHandler interface {
 	AddKnowledge(context.Context, *connect.Request[api.AddKnowledgeRequest]) (*connect.Response[api.AddKnowledgeResponse], error)
 	// ParseCodebase parses a file or directory
 	ParseCodebase(context.Context, *connect.Request[api.ParseCodebaseRequest]) (*connect.Response[api.ParseCodebaseResponse], error)
+	// ApplyFileChanges inserts or overwrites code fragments in go files, or previews the changes with dry_run
+	ApplyFileChanges(context.Context, *connect.Request[api.ApplyFileChangesRequest]) (*connect.Response[api.ApplyFileChangesResponse], error)
 }
 
 // NewGptServiceHandler builds an HTTP handler from the service implementation. It returns the path
@@ -251,6 +271,12 @@ func NewGptServiceHandler(svc GptServiceHandler, opts ...connect.HandlerOption)
 		connect.WithSchema(gptServiceParseCodebaseMethodDescriptor),
 		connect.WithHandlerOptions(opts...),
 	)
+	gptServiceApplyFileChangesHandler := connect.NewUnaryHandler(
+		GptServiceApplyFileChangesProcedure,
+		svc.ApplyFileChanges,
+		connect.WithSchema(gptServiceApplyFileChangesMethodDescriptor),
+		connect.WithHandlerOptions(opts...),
+	)
 	return "/codesurgeon.GptService/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
 		case GptServiceGetOpenAPIProcedure:
@@ -267,6 +293,8 @@ func NewGptServiceHandler(svc GptServiceHandler, opts ...connect.HandlerOption)
 			gptServiceAddKnowledgeHandler.ServeHTTP(w, r)
 		case GptServiceParseCodebaseProcedure:
 			gptServiceParseCodebaseHandler.ServeHTTP(w, r)
+		case GptServiceApplyFileChangesProcedure:
+			gptServiceApplyFileChangesHandler.ServeHTTP(w, r)
 		default:
 			http.NotFound(w, r)
 		}
@@ -303,3 +331,7 @@ func (UnimplementedGptServiceHandler) AddKnowledge(context.Context, *connect.Req
 func (UnimplementedGptServiceHandler) ParseCodebase(context.Context, *connect.Request[api.ParseCodebaseRequest]) (*connect.Response[api.ParseCodebaseResponse], error) {
 	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("codesurgeon.GptService.ParseCodebase is not implemented"))
 }
+
+func (UnimplementedGptServiceHandler) ApplyFileChanges(context.Context, *connect.Request[api.ApplyFileChangesRequest]) (*connect.Response[api.ApplyFileChangesResponse], error) {
+	return nil, connect.NewError(connect.CodeUnimplemented, errors.New("codesurgeon.GptService.ApplyFileChanges is not implemented"))
+}
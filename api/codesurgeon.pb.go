@@ -36,8 +36,15 @@ type ParseCodebaseRequest struct {
 	Comments                bool                   `protobuf:"varint,10,opt,name=comments,proto3" json:"comments,omitempty"`
 	Tags                    bool                   `protobuf:"varint,11,opt,name=tags,proto3" json:"tags,omitempty"`
 	IgnoreRule              []string               `protobuf:"bytes,12,rep,name=ignore_rule,json=ignoreRule,proto3" json:"ignore_rule,omitempty"`
-	unknownFields           protoimpl.UnknownFields
-	sizeCache               protoimpl.SizeCache
+	// Reuse the parsing of unchanged files from the parse cache of the module
+	UseCache bool `protobuf:"varint,13,opt,name=use_cache,json=useCache,proto3" json:"use_cache,omitempty"`
+	// Contents replacing files or adding files to directories, by path, e.g. code about to be written
+	Overlay map[string]string `protobuf:"bytes,14,rep,name=overlay,proto3" json:"overlay,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// Only print these struct tag keys, e.g. json and db, with the llm and text_long formats
+	// The llm format only prints tags with tag keys, "*" selecting every key
+	TagKeys       []string `protobuf:"bytes,15,rep,name=tag_keys,json=tagKeys,proto3" json:"tag_keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ParseCodebaseRequest) Reset() {
@@ -154,6 +161,27 @@ func (x *ParseCodebaseRequest) GetIgnoreRule() []string {
 	return nil
 }
 
+func (x *ParseCodebaseRequest) GetUseCache() bool {
+	if x != nil {
+		return x.UseCache
+	}
+	return false
+}
+
+func (x *ParseCodebaseRequest) GetOverlay() map[string]string {
+	if x != nil {
+		return x.Overlay
+	}
+	return nil
+}
+
+func (x *ParseCodebaseRequest) GetTagKeys() []string {
+	if x != nil {
+		return x.TagKeys
+	}
+	return nil
+}
+
 // Response message for ParseCodebase
 type ParseCodebaseResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -1053,6 +1081,370 @@ func (x *ExecuteNeo4JQueryResponse) GetResult() string {
 	return ""
 }
 
+// Code fragments to insert in a file, created with package_name when it doesn't exist
+type FileChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PackageName   string                 `protobuf:"bytes,1,opt,name=package_name,json=packageName,proto3" json:"package_name,omitempty"`
+	File          string                 `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`
+	Fragments     []*CodeFragment        `protobuf:"bytes,3,rep,name=fragments,proto3" json:"fragments,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileChange) Reset() {
+	*x = FileChange{}
+	mi := &file_api_codesurgeon_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileChange) ProtoMessage() {}
+
+func (x *FileChange) ProtoReflect() protoreflect.Message {
+	mi := &file_api_codesurgeon_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileChange.ProtoReflect.Descriptor instead.
+func (*FileChange) Descriptor() ([]byte, []int) {
+	return file_api_codesurgeon_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *FileChange) GetPackageName() string {
+	if x != nil {
+		return x.PackageName
+	}
+	return ""
+}
+
+func (x *FileChange) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *FileChange) GetFragments() []*CodeFragment {
+	if x != nil {
+		return x.Fragments
+	}
+	return nil
+}
+
+// Go declarations to insert in a file, replacing the existing ones with overwrite,
+// or declarations to remove with the "remove" operation
+type CodeFragment struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Content   string                 `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	Overwrite bool                   `protobuf:"varint,2,opt,name=overwrite,proto3" json:"overwrite,omitempty"`
+	// "upsert" (the default when empty) or "remove"
+	Operation string `protobuf:"bytes,3,opt,name=operation,proto3" json:"operation,omitempty"`
+	// Declarations removed by the "remove" operation
+	Selectors     []*DeclSelector `protobuf:"bytes,4,rep,name=selectors,proto3" json:"selectors,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CodeFragment) Reset() {
+	*x = CodeFragment{}
+	mi := &file_api_codesurgeon_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CodeFragment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CodeFragment) ProtoMessage() {}
+
+func (x *CodeFragment) ProtoReflect() protoreflect.Message {
+	mi := &file_api_codesurgeon_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CodeFragment.ProtoReflect.Descriptor instead.
+func (*CodeFragment) Descriptor() ([]byte, []int) {
+	return file_api_codesurgeon_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CodeFragment) GetContent() string {
+	if x != nil {
+		return x.Content
+	}
+	return ""
+}
+
+func (x *CodeFragment) GetOverwrite() bool {
+	if x != nil {
+		return x.Overwrite
+	}
+	return false
+}
+
+func (x *CodeFragment) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *CodeFragment) GetSelectors() []*DeclSelector {
+	if x != nil {
+		return x.Selectors
+	}
+	return nil
+}
+
+// A function, type, constant or variable by name, or a method by receiver type and name
+type DeclSelector struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Receiver      string                 `protobuf:"bytes,1,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeclSelector) Reset() {
+	*x = DeclSelector{}
+	mi := &file_api_codesurgeon_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeclSelector) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeclSelector) ProtoMessage() {}
+
+func (x *DeclSelector) ProtoReflect() protoreflect.Message {
+	mi := &file_api_codesurgeon_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeclSelector.ProtoReflect.Descriptor instead.
+func (*DeclSelector) Descriptor() ([]byte, []int) {
+	return file_api_codesurgeon_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *DeclSelector) GetReceiver() string {
+	if x != nil {
+		return x.Receiver
+	}
+	return ""
+}
+
+func (x *DeclSelector) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+// Request message for ApplyFileChanges
+type ApplyFileChangesRequest struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Changes []*FileChange          `protobuf:"bytes,1,rep,name=changes,proto3" json:"changes,omitempty"`
+	// Only return the previews of the files, without writing them
+	DryRun        bool `protobuf:"varint,2,opt,name=dry_run,json=dryRun,proto3" json:"dry_run,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApplyFileChangesRequest) Reset() {
+	*x = ApplyFileChangesRequest{}
+	mi := &file_api_codesurgeon_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApplyFileChangesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApplyFileChangesRequest) ProtoMessage() {}
+
+func (x *ApplyFileChangesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_api_codesurgeon_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApplyFileChangesRequest.ProtoReflect.Descriptor instead.
+func (*ApplyFileChangesRequest) Descriptor() ([]byte, []int) {
+	return file_api_codesurgeon_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ApplyFileChangesRequest) GetChanges() []*FileChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+func (x *ApplyFileChangesRequest) GetDryRun() bool {
+	if x != nil {
+		return x.DryRun
+	}
+	return false
+}
+
+// Content of a file once the changes are applied
+type FilePreview struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	File       string                 `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	OldContent string                 `protobuf:"bytes,2,opt,name=old_content,json=oldContent,proto3" json:"old_content,omitempty"`
+	NewContent string                 `protobuf:"bytes,3,opt,name=new_content,json=newContent,proto3" json:"new_content,omitempty"`
+	// Unified diff from old_content to new_content
+	Diff string `protobuf:"bytes,4,opt,name=diff,proto3" json:"diff,omitempty"`
+	// The file doesn't exist yet
+	IsNew         bool `protobuf:"varint,5,opt,name=is_new,json=isNew,proto3" json:"is_new,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FilePreview) Reset() {
+	*x = FilePreview{}
+	mi := &file_api_codesurgeon_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FilePreview) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FilePreview) ProtoMessage() {}
+
+func (x *FilePreview) ProtoReflect() protoreflect.Message {
+	mi := &file_api_codesurgeon_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FilePreview.ProtoReflect.Descriptor instead.
+func (*FilePreview) Descriptor() ([]byte, []int) {
+	return file_api_codesurgeon_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *FilePreview) GetFile() string {
+	if x != nil {
+		return x.File
+	}
+	return ""
+}
+
+func (x *FilePreview) GetOldContent() string {
+	if x != nil {
+		return x.OldContent
+	}
+	return ""
+}
+
+func (x *FilePreview) GetNewContent() string {
+	if x != nil {
+		return x.NewContent
+	}
+	return ""
+}
+
+func (x *FilePreview) GetDiff() string {
+	if x != nil {
+		return x.Diff
+	}
+	return ""
+}
+
+func (x *FilePreview) GetIsNew() bool {
+	if x != nil {
+		return x.IsNew
+	}
+	return false
+}
+
+// Response message for ApplyFileChanges
+type ApplyFileChangesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Files         []*FilePreview         `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ApplyFileChangesResponse) Reset() {
+	*x = ApplyFileChangesResponse{}
+	mi := &file_api_codesurgeon_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ApplyFileChangesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ApplyFileChangesResponse) ProtoMessage() {}
+
+func (x *ApplyFileChangesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_api_codesurgeon_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ApplyFileChangesResponse.ProtoReflect.Descriptor instead.
+func (*ApplyFileChangesResponse) Descriptor() ([]byte, []int) {
+	return file_api_codesurgeon_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ApplyFileChangesResponse) GetFiles() []*FilePreview {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
 type SearchSimilarFunctionsResponse_Function struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Code          string                 `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
@@ -1062,7 +1454,7 @@ type SearchSimilarFunctionsResponse_Function struct {
 
 func (x *SearchSimilarFunctionsResponse_Function) Reset() {
 	*x = SearchSimilarFunctionsResponse_Function{}
-	mi := &file_api_codesurgeon_proto_msgTypes[19]
+	mi := &file_api_codesurgeon_proto_msgTypes[26]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -1074,7 +1466,7 @@ func (x *SearchSimilarFunctionsResponse_Function) String() string {
 func (*SearchSimilarFunctionsResponse_Function) ProtoMessage() {}
 
 func (x *SearchSimilarFunctionsResponse_Function) ProtoReflect() protoreflect.Message {
-	mi := &file_api_codesurgeon_proto_msgTypes[19]
+	mi := &file_api_codesurgeon_proto_msgTypes[26]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1101,7 +1493,7 @@ var File_api_codesurgeon_proto protoreflect.FileDescriptor
 
 const file_api_codesurgeon_proto_rawDesc = "" +
 	"\n" +
-	"\x15api/codesurgeon.proto\x12\vcodesurgeon\"\xad\x03\n" +
+	"\x15api/codesurgeon.proto\x12\vcodesurgeon\"\xeb\x04\n" +
 	"\x14ParseCodebaseRequest\x12\x12\n" +
 	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1c\n" +
 	"\trecursive\x18\x02 \x01(\bR\trecursive\x12\x16\n" +
@@ -1116,7 +1508,13 @@ const file_api_codesurgeon_proto_rawDesc = "" +
 	" \x01(\bR\bcomments\x12\x12\n" +
 	"\x04tags\x18\v \x01(\bR\x04tags\x12\x1f\n" +
 	"\vignore_rule\x18\f \x03(\tR\n" +
-	"ignoreRule\"8\n" +
+	"ignoreRule\x12\x1b\n" +
+	"\tuse_cache\x18\r \x01(\bR\buseCache\x12H\n" +
+	"\aoverlay\x18\x0e \x03(\v2..codesurgeon.ParseCodebaseRequest.OverlayEntryR\aoverlay\x12\x19\n" +
+	"\btag_keys\x18\x0f \x03(\tR\atagKeys\x1a:\n" +
+	"\fOverlayEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"8\n" +
 	"\x15ParseCodebaseResponse\x12\x1f\n" +
 	"\vparsed_info\x18\x01 \x01(\tR\n" +
 	"parsedInfo\"D\n" +
@@ -1169,7 +1567,33 @@ const file_api_codesurgeon_proto_rawDesc = "" +
 	"\x18ExecuteNeo4jQueryRequest\x12\x14\n" +
 	"\x05query\x18\x01 \x01(\tR\x05query\"3\n" +
 	"\x19ExecuteNeo4jQueryResponse\x12\x16\n" +
-	"\x06result\x18\x01 \x01(\tR\x06result2\xa4\x05\n" +
+	"\x06result\x18\x01 \x01(\tR\x06result\"|\n" +
+	"\n" +
+	"FileChange\x12!\n" +
+	"\fpackage_name\x18\x01 \x01(\tR\vpackageName\x12\x12\n" +
+	"\x04file\x18\x02 \x01(\tR\x04file\x127\n" +
+	"\tfragments\x18\x03 \x03(\v2\x19.codesurgeon.CodeFragmentR\tfragments\"\x9d\x01\n" +
+	"\fCodeFragment\x12\x18\n" +
+	"\acontent\x18\x01 \x01(\tR\acontent\x12\x1c\n" +
+	"\toverwrite\x18\x02 \x01(\bR\toverwrite\x12\x1c\n" +
+	"\toperation\x18\x03 \x01(\tR\toperation\x127\n" +
+	"\tselectors\x18\x04 \x03(\v2\x19.codesurgeon.DeclSelectorR\tselectors\">\n" +
+	"\fDeclSelector\x12\x1a\n" +
+	"\breceiver\x18\x01 \x01(\tR\breceiver\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\"e\n" +
+	"\x17ApplyFileChangesRequest\x121\n" +
+	"\achanges\x18\x01 \x03(\v2\x17.codesurgeon.FileChangeR\achanges\x12\x17\n" +
+	"\adry_run\x18\x02 \x01(\bR\x06dryRun\"\x8e\x01\n" +
+	"\vFilePreview\x12\x12\n" +
+	"\x04file\x18\x01 \x01(\tR\x04file\x12\x1f\n" +
+	"\vold_content\x18\x02 \x01(\tR\n" +
+	"oldContent\x12\x1f\n" +
+	"\vnew_content\x18\x03 \x01(\tR\n" +
+	"newContent\x12\x12\n" +
+	"\x04diff\x18\x04 \x01(\tR\x04diff\x12\x15\n" +
+	"\x06is_new\x18\x05 \x01(\bR\x05isNew\"J\n" +
+	"\x18ApplyFileChangesResponse\x12.\n" +
+	"\x05files\x18\x01 \x03(\v2\x18.codesurgeon.FilePreviewR\x05files2\x85\x06\n" +
 	"\n" +
 	"GptService\x12M\n" +
 	"\n" +
@@ -1179,7 +1603,8 @@ const file_api_codesurgeon_proto_rawDesc = "" +
 	"\x11ExecuteNeo4jQuery\x12%.codesurgeon.ExecuteNeo4jQueryRequest\x1a&.codesurgeon.ExecuteNeo4jQueryResponse\x12h\n" +
 	"\x13ThinkThroughProblem\x12'.codesurgeon.ThinkThroughProblemRequest\x1a(.codesurgeon.ThinkThroughProblemResponse\x12S\n" +
 	"\fAddKnowledge\x12 .codesurgeon.AddKnowledgeRequest\x1a!.codesurgeon.AddKnowledgeResponse\x12V\n" +
-	"\rParseCodebase\x12!.codesurgeon.ParseCodebaseRequest\x1a\".codesurgeon.ParseCodebaseResponseB\x95\x01\n" +
+	"\rParseCodebase\x12!.codesurgeon.ParseCodebaseRequest\x1a\".codesurgeon.ParseCodebaseResponse\x12_\n" +
+	"\x10ApplyFileChanges\x12$.codesurgeon.ApplyFileChangesRequest\x1a%.codesurgeon.ApplyFileChangesResponseB\x95\x01\n" +
 	"\x0fcom.codesurgeonB\x10CodesurgeonProtoP\x01Z$github.com/wricardo/code-surgeon/api\xa2\x02\x03CXX\xaa\x02\vCodesurgeon\xca\x02\vCodesurgeon\xe2\x02\x17Codesurgeon\\GPBMetadata\xea\x02\vCodesurgeonb\x06proto3"
 
 var (
@@ -1194,7 +1619,7 @@ func file_api_codesurgeon_proto_rawDescGZIP() []byte {
 	return file_api_codesurgeon_proto_rawDescData
 }
 
-var file_api_codesurgeon_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_api_codesurgeon_proto_msgTypes = make([]protoimpl.MessageInfo, 27)
 var file_api_codesurgeon_proto_goTypes = []any{
 	(*ParseCodebaseRequest)(nil),                    // 0: codesurgeon.ParseCodebaseRequest
 	(*ParseCodebaseResponse)(nil),                   // 1: codesurgeon.ParseCodebaseResponse
@@ -1215,36 +1640,50 @@ var file_api_codesurgeon_proto_goTypes = []any{
 	(*AddKnowledgeResponse)(nil),                    // 16: codesurgeon.AddKnowledgeResponse
 	(*ExecuteNeo4JQueryRequest)(nil),                // 17: codesurgeon.ExecuteNeo4jQueryRequest
 	(*ExecuteNeo4JQueryResponse)(nil),               // 18: codesurgeon.ExecuteNeo4jQueryResponse
-	(*SearchSimilarFunctionsResponse_Function)(nil), // 19: codesurgeon.SearchSimilarFunctionsResponse.Function
+	(*FileChange)(nil),                              // 19: codesurgeon.FileChange
+	(*CodeFragment)(nil),                            // 20: codesurgeon.CodeFragment
+	(*DeclSelector)(nil),                            // 21: codesurgeon.DeclSelector
+	(*ApplyFileChangesRequest)(nil),                 // 22: codesurgeon.ApplyFileChangesRequest
+	(*FilePreview)(nil),                             // 23: codesurgeon.FilePreview
+	(*ApplyFileChangesResponse)(nil),                // 24: codesurgeon.ApplyFileChangesResponse
+	nil,                                             // 25: codesurgeon.ParseCodebaseRequest.OverlayEntry
+	(*SearchSimilarFunctionsResponse_Function)(nil), // 26: codesurgeon.SearchSimilarFunctionsResponse.Function
 }
 var file_api_codesurgeon_proto_depIdxs = []int32{
-	19, // 0: codesurgeon.SearchSimilarFunctionsResponse.functions:type_name -> codesurgeon.SearchSimilarFunctionsResponse.Function
-	9,  // 1: codesurgeon.GetNeo4jSchemaResponse.schema:type_name -> codesurgeon.Schema
-	10, // 2: codesurgeon.Schema.labels:type_name -> codesurgeon.LabelSchema
-	12, // 3: codesurgeon.Schema.relationships:type_name -> codesurgeon.RelationshipSchema
-	11, // 4: codesurgeon.LabelSchema.properties:type_name -> codesurgeon.PropertySchema
-	2,  // 5: codesurgeon.ThinkThroughProblemResponse.answers:type_name -> codesurgeon.QuestionAnswer
-	2,  // 6: codesurgeon.ThinkThroughProblemResponse.similar_questions:type_name -> codesurgeon.QuestionAnswer
-	2,  // 7: codesurgeon.AddKnowledgeRequest.question_answer:type_name -> codesurgeon.QuestionAnswer
-	3,  // 8: codesurgeon.GptService.GetOpenAPI:input_type -> codesurgeon.GetOpenAPIRequest
-	5,  // 9: codesurgeon.GptService.SearchSimilarFunctions:input_type -> codesurgeon.SearchSimilarFunctionsRequest
-	7,  // 10: codesurgeon.GptService.GetNeo4jSchema:input_type -> codesurgeon.GetNeo4jSchemaRequest
-	17, // 11: codesurgeon.GptService.ExecuteNeo4jQuery:input_type -> codesurgeon.ExecuteNeo4jQueryRequest
-	13, // 12: codesurgeon.GptService.ThinkThroughProblem:input_type -> codesurgeon.ThinkThroughProblemRequest
-	15, // 13: codesurgeon.GptService.AddKnowledge:input_type -> codesurgeon.AddKnowledgeRequest
-	0,  // 14: codesurgeon.GptService.ParseCodebase:input_type -> codesurgeon.ParseCodebaseRequest
-	4,  // 15: codesurgeon.GptService.GetOpenAPI:output_type -> codesurgeon.GetOpenAPIResponse
-	6,  // 16: codesurgeon.GptService.SearchSimilarFunctions:output_type -> codesurgeon.SearchSimilarFunctionsResponse
-	8,  // 17: codesurgeon.GptService.GetNeo4jSchema:output_type -> codesurgeon.GetNeo4jSchemaResponse
-	18, // 18: codesurgeon.GptService.ExecuteNeo4jQuery:output_type -> codesurgeon.ExecuteNeo4jQueryResponse
-	14, // 19: codesurgeon.GptService.ThinkThroughProblem:output_type -> codesurgeon.ThinkThroughProblemResponse
-	16, // 20: codesurgeon.GptService.AddKnowledge:output_type -> codesurgeon.AddKnowledgeResponse
-	1,  // 21: codesurgeon.GptService.ParseCodebase:output_type -> codesurgeon.ParseCodebaseResponse
-	15, // [15:22] is the sub-list for method output_type
-	8,  // [8:15] is the sub-list for method input_type
-	8,  // [8:8] is the sub-list for extension type_name
-	8,  // [8:8] is the sub-list for extension extendee
-	0,  // [0:8] is the sub-list for field type_name
+	25, // 0: codesurgeon.ParseCodebaseRequest.overlay:type_name -> codesurgeon.ParseCodebaseRequest.OverlayEntry
+	26, // 1: codesurgeon.SearchSimilarFunctionsResponse.functions:type_name -> codesurgeon.SearchSimilarFunctionsResponse.Function
+	9,  // 2: codesurgeon.GetNeo4jSchemaResponse.schema:type_name -> codesurgeon.Schema
+	10, // 3: codesurgeon.Schema.labels:type_name -> codesurgeon.LabelSchema
+	12, // 4: codesurgeon.Schema.relationships:type_name -> codesurgeon.RelationshipSchema
+	11, // 5: codesurgeon.LabelSchema.properties:type_name -> codesurgeon.PropertySchema
+	2,  // 6: codesurgeon.ThinkThroughProblemResponse.answers:type_name -> codesurgeon.QuestionAnswer
+	2,  // 7: codesurgeon.ThinkThroughProblemResponse.similar_questions:type_name -> codesurgeon.QuestionAnswer
+	2,  // 8: codesurgeon.AddKnowledgeRequest.question_answer:type_name -> codesurgeon.QuestionAnswer
+	20, // 9: codesurgeon.FileChange.fragments:type_name -> codesurgeon.CodeFragment
+	21, // 10: codesurgeon.CodeFragment.selectors:type_name -> codesurgeon.DeclSelector
+	19, // 11: codesurgeon.ApplyFileChangesRequest.changes:type_name -> codesurgeon.FileChange
+	23, // 12: codesurgeon.ApplyFileChangesResponse.files:type_name -> codesurgeon.FilePreview
+	3,  // 13: codesurgeon.GptService.GetOpenAPI:input_type -> codesurgeon.GetOpenAPIRequest
+	5,  // 14: codesurgeon.GptService.SearchSimilarFunctions:input_type -> codesurgeon.SearchSimilarFunctionsRequest
+	7,  // 15: codesurgeon.GptService.GetNeo4jSchema:input_type -> codesurgeon.GetNeo4jSchemaRequest
+	17, // 16: codesurgeon.GptService.ExecuteNeo4jQuery:input_type -> codesurgeon.ExecuteNeo4jQueryRequest
+	13, // 17: codesurgeon.GptService.ThinkThroughProblem:input_type -> codesurgeon.ThinkThroughProblemRequest
+	15, // 18: codesurgeon.GptService.AddKnowledge:input_type -> codesurgeon.AddKnowledgeRequest
+	0,  // 19: codesurgeon.GptService.ParseCodebase:input_type -> codesurgeon.ParseCodebaseRequest
+	22, // 20: codesurgeon.GptService.ApplyFileChanges:input_type -> codesurgeon.ApplyFileChangesRequest
+	4,  // 21: codesurgeon.GptService.GetOpenAPI:output_type -> codesurgeon.GetOpenAPIResponse
+	6,  // 22: codesurgeon.GptService.SearchSimilarFunctions:output_type -> codesurgeon.SearchSimilarFunctionsResponse
+	8,  // 23: codesurgeon.GptService.GetNeo4jSchema:output_type -> codesurgeon.GetNeo4jSchemaResponse
+	18, // 24: codesurgeon.GptService.ExecuteNeo4jQuery:output_type -> codesurgeon.ExecuteNeo4jQueryResponse
+	14, // 25: codesurgeon.GptService.ThinkThroughProblem:output_type -> codesurgeon.ThinkThroughProblemResponse
+	16, // 26: codesurgeon.GptService.AddKnowledge:output_type -> codesurgeon.AddKnowledgeResponse
+	1,  // 27: codesurgeon.GptService.ParseCodebase:output_type -> codesurgeon.ParseCodebaseResponse
+	24, // 28: codesurgeon.GptService.ApplyFileChanges:output_type -> codesurgeon.ApplyFileChangesResponse
+	21, // [21:29] is the sub-list for method output_type
+	13, // [13:21] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_api_codesurgeon_proto_init() }
@@ -1258,7 +1697,7 @@ func file_api_codesurgeon_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_api_codesurgeon_proto_rawDesc), len(file_api_codesurgeon_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   20,
+			NumMessages:   27,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
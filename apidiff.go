@@ -0,0 +1,426 @@
+package codesurgeon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/token"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Changes of the exported API of a module between two revisions.
+const (
+	APIAdded   = "added"
+	APIRemoved = "removed"
+	APIChanged = "changed"
+)
+
+// Kinds of the declarations of the exported API.
+const (
+	APIKindType            = "type"
+	APIKindFunction        = "function"
+	APIKindMethod          = "method"
+	APIKindField           = "field"
+	APIKindInterfaceMethod = "interface method"
+)
+
+// APISymbol is an exported declaration of the API of a module.
+type APISymbol struct {
+	Package   string `json:"package"`   // Import path of the package
+	Name      string `json:"name"`      // e.g. "Buy", "Cart.Add" for methods and "Order.ID" for fields
+	Kind      string `json:"kind"`      // One of the APIKind constants
+	Signature string `json:"signature"` // Declaration without parameter names, compared between revisions, e.g. "func Buy(int) error"
+}
+
+// APIChange is a declaration added, removed or changed between two revisions of the API of a module.
+type APIChange struct {
+	Change   string `json:"change"` // APIAdded, APIRemoved or APIChanged
+	Package  string `json:"package"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	Before   string `json:"before,omitempty"` // Signature at the base revision
+	After    string `json:"after,omitempty"`  // Signature at the head revision
+	Breaking bool   `json:"breaking"`         // Code using the base revision may not compile with the head revision
+}
+
+// APIDiff holds the changes of the exported API of a module between the base and head revisions.
+type APIDiff struct {
+	Base    string      `json:"base"`
+	Head    string      `json:"head"`
+	Changes []APIChange `json:"changes"`
+}
+
+// Breaking reports whether any of the changes is breaking.
+func (d APIDiff) Breaking() bool {
+	for _, c := range d.Changes {
+		if c.Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtractAPI returns the exported API of the parsed packages: their exported types, functions,
+// methods, struct fields and interface methods, sorted by package and name. Test, main and
+// internal packages aren't part of the API.
+func ExtractAPI(parsed []*ParsedInfo) []APISymbol {
+	var symbols []APISymbol
+	for _, info := range parsed {
+		for _, pkg := range info.Packages {
+			if !isAPIPackage(pkg) {
+				continue
+			}
+			add := func(name, kind, signature string) {
+				symbols = append(symbols, APISymbol{Package: pkg.ImportPath, Name: name, Kind: kind, Signature: signature})
+			}
+			for _, s := range pkg.Structs {
+				if !token.IsExported(s.Name) {
+					continue
+				}
+				add(s.Name, APIKindType, strings.TrimSpace(formatTypeParamList(s.TypeParams)+" struct"))
+				for _, f := range s.Fields {
+					for _, name := range fieldNames(f) {
+						if token.IsExported(name) {
+							add(s.Name+"."+name, APIKindField, apiFieldSignature(f))
+						}
+					}
+				}
+				addAPIMethods(add, s.Name, s.Methods)
+			}
+			for _, i := range pkg.Interfaces {
+				if !token.IsExported(i.Name) {
+					continue
+				}
+				add(i.Name, APIKindType, strings.TrimSpace(formatTypeParamList(i.TypeParams)+" interface"))
+				for _, m := range i.Methods {
+					if token.IsExported(m.Name) {
+						add(i.Name+"."+m.Name, APIKindInterfaceMethod, m.Name+apiFuncSignature(nil, m.Params, m.Returns))
+					}
+				}
+			}
+			for _, t := range pkg.Types {
+				if !token.IsExported(t.Name) {
+					continue
+				}
+				underlying := t.Underlying
+				if t.IsAlias {
+					underlying = "= " + underlying
+				}
+				add(t.Name, APIKindType, strings.TrimSpace(formatTypeParamList(t.TypeParams)+" "+underlying))
+				addAPIMethods(add, t.Name, t.Methods)
+			}
+			for _, f := range pkg.Functions {
+				if token.IsExported(f.Name) {
+					add(f.Name, APIKindFunction, "func "+f.Name+apiFuncSignature(f.TypeParams, f.Params, f.Returns))
+				}
+			}
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool {
+		if symbols[i].Package != symbols[j].Package {
+			return symbols[i].Package < symbols[j].Package
+		}
+		return symbols[i].Name < symbols[j].Name
+	})
+	return symbols
+}
+
+// addAPIMethods adds the exported methods declared on the typeName type.
+func addAPIMethods(add func(name, kind, signature string), typeName string, methods []Method) {
+	for _, m := range methods {
+		if token.IsExported(m.Name) {
+			add(typeName+"."+m.Name, APIKindMethod, "func ("+m.Receiver+") "+m.Name+apiFuncSignature(nil, m.Params, m.Returns))
+		}
+	}
+}
+
+// isAPIPackage reports whether pkg is importable by other modules.
+func isAPIPackage(pkg Package) bool {
+	if pkg.IsTest || pkg.Package == "main" || strings.HasSuffix(pkg.Package, "_test") {
+		return false
+	}
+	for _, elem := range strings.Split(pkg.ImportPath, "/") {
+		if elem == "internal" {
+			return false
+		}
+	}
+	return true
+}
+
+// apiFieldSignature returns the type of a field, marked as embedded when it is.
+func apiFieldSignature(f Field) string {
+	if f.Embedded {
+		return "embedded " + f.Type
+	}
+	return f.Type
+}
+
+// apiFuncSignature returns the type parameters, parameters and results of a function without
+// their names, e.g. "(int, string) error", so that renaming a parameter isn't a change.
+func apiFuncSignature(typeParams []TypeParam, params []Param, returns []Param) string {
+	types := func(list []Param) string {
+		parts := make([]string, 0, len(list))
+		for _, p := range list {
+			parts = append(parts, p.Type)
+		}
+		return strings.Join(parts, ", ")
+	}
+	signature := formatTypeParamList(typeParams) + "(" + types(params) + ")"
+	switch len(returns) {
+	case 0:
+	case 1:
+		signature += " " + types(returns)
+	default:
+		signature += " (" + types(returns) + ")"
+	}
+	return signature
+}
+
+// DiffAPI compares the base and head revisions of an API, as returned by ExtractAPI. Removing or
+// changing a declaration is breaking, and so is adding a method to an interface since the types
+// implementing it don't anymore. Like apidiff, adding a trailing variadic parameter to a function
+// or method isn't breaking since the calls compile unchanged.
+func DiffAPI(base, head []APISymbol) []APIChange {
+	key := func(s APISymbol) string {
+		return s.Package + "\x00" + s.Kind + "\x00" + s.Name
+	}
+	before := make(map[string]APISymbol, len(base))
+	for _, s := range base {
+		before[key(s)] = s
+	}
+
+	var changes []APIChange
+	seen := make(map[string]bool, len(head))
+	for _, s := range head {
+		k := key(s)
+		seen[k] = true
+		old, ok := before[k]
+		switch {
+		case !ok:
+			changes = append(changes, APIChange{Change: APIAdded, Package: s.Package, Name: s.Name, Kind: s.Kind, After: s.Signature, Breaking: s.Kind == APIKindInterfaceMethod})
+		case old.Signature != s.Signature:
+			changes = append(changes, APIChange{Change: APIChanged, Package: s.Package, Name: s.Name, Kind: s.Kind, Before: old.Signature, After: s.Signature, Breaking: !addsVariadicParam(old, s)})
+		}
+	}
+	for _, s := range base {
+		if !seen[key(s)] {
+			changes = append(changes, APIChange{Change: APIRemoved, Package: s.Package, Name: s.Name, Kind: s.Kind, Before: s.Signature, Breaking: true})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Package != changes[j].Package {
+			return changes[i].Package < changes[j].Package
+		}
+		return changes[i].Name < changes[j].Name
+	})
+	return changes
+}
+
+// addsVariadicParam reports whether the function or method head only differs from base by a
+// trailing variadic parameter, e.g. "func F(int)" and "func F(int, ...string)".
+func addsVariadicParam(base, head APISymbol) bool {
+	if base.Kind != APIKindFunction && base.Kind != APIKindMethod {
+		return false
+	}
+	end := paramListEnd(base.Signature)
+	if end < 0 || len(head.Signature) <= len(base.Signature) || !strings.HasPrefix(head.Signature, base.Signature[:end]) || !strings.HasSuffix(head.Signature, base.Signature[end:]) {
+		return false
+	}
+	added := head.Signature[end : len(head.Signature)-len(base.Signature)+end]
+	if base.Signature[end-1] != '(' {
+		var ok bool
+		if added, ok = strings.CutPrefix(added, ", "); !ok {
+			return false
+		}
+	}
+	return strings.HasPrefix(added, "...") && paramListEnd(head.Signature) == len(head.Signature)-len(base.Signature)+end
+}
+
+// paramListEnd returns the index of the parenthesis closing the parameters of a function or method
+// signature as built by ExtractAPI, or -1.
+func paramListEnd(signature string) int {
+	i := len("func ")
+	if !strings.HasPrefix(signature, "func ") {
+		return -1
+	}
+	// closing returns the index of the bracket closing the one at start
+	closing := func(start int) int {
+		depth := 0
+		for j := start; j < len(signature); j++ {
+			switch signature[j] {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				depth--
+				if depth == 0 {
+					return j
+				}
+			}
+		}
+		return -1
+	}
+	if i < len(signature) && signature[i] == '(' { // Receiver
+		if i = closing(i); i < 0 {
+			return -1
+		}
+		i += len(") ")
+	}
+	for i < len(signature) && signature[i] != '(' && signature[i] != '[' {
+		i++
+	}
+	if i < len(signature) && signature[i] == '[' { // Type parameters
+		if i = closing(i) + 1; i <= 0 {
+			return -1
+		}
+	}
+	if i >= len(signature) || signature[i] != '(' {
+		return -1
+	}
+	return closing(i)
+}
+
+// DiffAPIRevisions compares the exported API of the module at dir between the base and head git
+// revisions, e.g. a tag and HEAD. Each revision is checked out in a temporary git worktree.
+func DiffAPIRevisions(ctx context.Context, dir string, base string, head string) (APIDiff, error) {
+	before, err := ParseAPIAtRevision(ctx, dir, base)
+	if err != nil {
+		return APIDiff{}, err
+	}
+	after, err := ParseAPIAtRevision(ctx, dir, head)
+	if err != nil {
+		return APIDiff{}, err
+	}
+	return APIDiff{Base: base, Head: head, Changes: DiffAPI(before, after)}, nil
+}
+
+// ParseAPIAtRevision returns the exported API of the module at dir as of the git revision ref,
+// checked out in a temporary git worktree removed afterwards. Like the go command, testdata,
+// vendor and directories starting with . or _ are skipped. The packages that can't be parsed
+// are all reported in the returned error.
+func ParseAPIAtRevision(ctx context.Context, dir string, ref string) ([]APISymbol, error) {
+	prefix, err := runGit(ctx, dir, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp("", "code-surgeon-api-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	worktree := filepath.Join(tmp, "worktree")
+	if _, err := runGit(ctx, dir, "worktree", "add", "--detach", worktree, ref); err != nil {
+		return nil, err
+	}
+	defer runGit(context.Background(), dir, "worktree", "remove", "--force", worktree)
+
+	parsed, err := ParseModules(ctx, filepath.Join(worktree, prefix), ParseOptions{AllFiles: true})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", ref, err)
+	}
+	var errs []error
+	for _, info := range parsed {
+		for _, pkgErr := range info.Errors {
+			pkgErr.Directory = strings.TrimPrefix(pkgErr.Directory, worktree+string(filepath.Separator))
+			errs = append(errs, pkgErr)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("error parsing %s: %w", ref, errors.Join(errs...))
+	}
+	return ExtractAPI(parsed), nil
+}
+
+// runGit runs a git command in dir and returns its trimmed output.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return "", fmt.Errorf("git %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// FormatAPIDiff formats an APIDiff as text, json or markdown, listing the breaking changes first.
+func FormatAPIDiff(diff APIDiff, format string) (string, error) {
+	var breaking, compatible []APIChange
+	for _, c := range diff.Changes {
+		if c.Breaking {
+			breaking = append(breaking, c)
+		} else {
+			compatible = append(compatible, c)
+		}
+	}
+
+	var sb strings.Builder
+	switch format {
+	case "json":
+		if diff.Changes == nil {
+			diff.Changes = []APIChange{}
+		}
+		out, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	case "text", "":
+		fmt.Fprintf(&sb, "API changes from %s to %s\n", diff.Base, diff.Head)
+		if len(diff.Changes) == 0 {
+			sb.WriteString("No changes\n")
+		}
+		for _, section := range []struct {
+			title   string
+			changes []APIChange
+		}{{"Breaking changes", breaking}, {"Compatible changes", compatible}} {
+			if len(section.changes) == 0 {
+				continue
+			}
+			fmt.Fprintf(&sb, "%s:\n", section.title)
+			for _, c := range section.changes {
+				fmt.Fprintf(&sb, "  %s %s %s.%s: %s\n", c.Change, c.Kind, c.Package, c.Name, formatAPIChangeSignature(c))
+			}
+		}
+		return sb.String(), nil
+	case "markdown":
+		fmt.Fprintf(&sb, "# API changes from `%s` to `%s`\n", diff.Base, diff.Head)
+		if len(diff.Changes) == 0 {
+			sb.WriteString("\nNo changes\n")
+		}
+		for _, section := range []struct {
+			title   string
+			changes []APIChange
+		}{{"Breaking changes", breaking}, {"Compatible changes", compatible}} {
+			if len(section.changes) == 0 {
+				continue
+			}
+			fmt.Fprintf(&sb, "\n## %s\n\n", section.title)
+			sb.WriteString("| Change | Kind | Declaration | Signature |\n|---|---|---|---|\n")
+			for _, c := range section.changes {
+				fmt.Fprintf(&sb, "| %s | %s | `%s.%s` | `%s` |\n", c.Change, c.Kind, c.Package, c.Name, strings.ReplaceAll(formatAPIChangeSignature(c), "|", "\\|"))
+			}
+		}
+		return sb.String(), nil
+	default:
+		return "", fmt.Errorf("invalid format: %s", format)
+	}
+}
+
+// formatAPIChangeSignature returns the signature of a change, "before -> after" for changed declarations.
+func formatAPIChangeSignature(c APIChange) string {
+	switch c.Change {
+	case APIAdded:
+		return c.After
+	case APIRemoved:
+		return c.Before
+	}
+	return c.Before + " -> " + c.After
+}
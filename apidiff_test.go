@@ -0,0 +1,193 @@
+package codesurgeon
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const apiDiffBase = `package shop
+
+type Cart struct {
+	Items []string
+	Owner string
+	total int
+}
+
+func (c *Cart) Add(item string) {}
+func (c *Cart) Total() int { return c.total }
+
+type Store interface {
+	Get(id string) (*Cart, error)
+}
+
+type Status string
+
+func NewCart(owner string) *Cart { return nil }
+func Checkout(c *Cart) error     { return nil }
+func helper()                    {}
+`
+
+const apiDiffHead = `package shop
+
+type Cart struct {
+	Items []string
+	Owner int
+	Note  string
+}
+
+func (c *Cart) Add(name string) {}
+func (c *Cart) Clear()          {}
+
+type Store interface {
+	Get(id string) (*Cart, error)
+	Put(c *Cart) error
+}
+
+type Status int
+
+func NewCart(name string) *Cart { return nil }
+func secret()                   {}
+`
+
+func TestDiffAPI(t *testing.T) {
+	base, err := ParseString(apiDiffBase)
+	require.NoError(t, err)
+	head, err := ParseString(apiDiffHead)
+	require.NoError(t, err)
+
+	api := ExtractAPI([]*ParsedInfo{base})
+	require.Contains(t, api, APISymbol{Package: "shop", Name: "Cart.Add", Kind: APIKindMethod, Signature: "func (*Cart) Add(string)"})
+	require.Contains(t, api, APISymbol{Package: "shop", Name: "Store.Get", Kind: APIKindInterfaceMethod, Signature: "Get(string) (*Cart, error)"})
+	for _, s := range api {
+		require.NotEqual(t, "helper", s.Name)
+		require.NotEqual(t, "Cart.total", s.Name)
+	}
+
+	changes := DiffAPI(api, ExtractAPI([]*ParsedInfo{head}))
+	require.Equal(t, []APIChange{
+		{Change: APIAdded, Package: "shop", Name: "Cart.Clear", Kind: APIKindMethod, After: "func (*Cart) Clear()"},
+		{Change: APIAdded, Package: "shop", Name: "Cart.Note", Kind: APIKindField, After: "string"},
+		{Change: APIChanged, Package: "shop", Name: "Cart.Owner", Kind: APIKindField, Before: "string", After: "int", Breaking: true},
+		{Change: APIRemoved, Package: "shop", Name: "Cart.Total", Kind: APIKindMethod, Before: "func (*Cart) Total() int", Breaking: true},
+		{Change: APIRemoved, Package: "shop", Name: "Checkout", Kind: APIKindFunction, Before: "func Checkout(*Cart) error", Breaking: true},
+		{Change: APIChanged, Package: "shop", Name: "Status", Kind: APIKindType, Before: "string", After: "int", Breaking: true},
+		{Change: APIAdded, Package: "shop", Name: "Store.Put", Kind: APIKindInterfaceMethod, After: "Put(*Cart) error", Breaking: true},
+	}, changes)
+
+	diff := APIDiff{Base: "v1", Head: "v2", Changes: changes}
+	require.True(t, diff.Breaking())
+
+	text, err := FormatAPIDiff(diff, "text")
+	require.NoError(t, err)
+	require.Contains(t, text, "Breaking changes:\n  changed field shop.Cart.Owner: string -> int\n")
+	require.Contains(t, text, "Compatible changes:\n  added method shop.Cart.Clear: func (*Cart) Clear()\n")
+
+	markdown, err := FormatAPIDiff(diff, "markdown")
+	require.NoError(t, err)
+	require.Contains(t, markdown, "# API changes from `v1` to `v2`\n")
+	require.Contains(t, markdown, "| removed | function | `shop.Checkout` | `func Checkout(*Cart) error` |\n")
+
+	out, err := FormatAPIDiff(diff, "json")
+	require.NoError(t, err)
+	var decoded APIDiff
+	require.NoError(t, json.Unmarshal([]byte(out), &decoded))
+	require.Equal(t, diff, decoded)
+
+	_, err = FormatAPIDiff(diff, "yaml")
+	require.Error(t, err)
+}
+
+func TestDiffAPI_Variadic(t *testing.T) {
+	base, err := ParseString(`package pretty
+
+type Printer struct{}
+
+func (p Printer) Print(v any) string { return "" }
+
+type Writer interface {
+	Write(v any) error
+}
+
+func Flush()                       {}
+func PrettyPrint(v any) string     { return "" }
+func Join(sep string) string       { return "" }
+`)
+	require.NoError(t, err)
+	head, err := ParseString(`package pretty
+
+type Printer struct{}
+
+func (p Printer) Print(v any, keys ...string) string { return "" }
+
+type Writer interface {
+	Write(v any, opts ...string) error
+}
+
+func Flush(opts ...string)                        {}
+func PrettyPrint(v any, tagKeys ...string) string { return "" }
+func Join(sep string, elems ...string) error      { return nil }
+`)
+	require.NoError(t, err)
+
+	changes := DiffAPI(ExtractAPI([]*ParsedInfo{base}), ExtractAPI([]*ParsedInfo{head}))
+	require.Equal(t, []APIChange{
+		{Change: APIChanged, Package: "pretty", Name: "Flush", Kind: APIKindFunction, Before: "func Flush()", After: "func Flush(...string)"},
+		{Change: APIChanged, Package: "pretty", Name: "Join", Kind: APIKindFunction, Before: "func Join(string) string", After: "func Join(string, ...string) error", Breaking: true},
+		{Change: APIChanged, Package: "pretty", Name: "PrettyPrint", Kind: APIKindFunction, Before: "func PrettyPrint(any) string", After: "func PrettyPrint(any, ...string) string"},
+		{Change: APIChanged, Package: "pretty", Name: "Printer.Print", Kind: APIKindMethod, Before: "func (Printer) Print(any) string", After: "func (Printer) Print(any, ...string) string"},
+		{Change: APIChanged, Package: "pretty", Name: "Writer.Write", Kind: APIKindInterfaceMethod, Before: "Write(any) error", After: "Write(any, ...string) error", Breaking: true},
+	}, changes)
+}
+
+func TestDiffAPIRevisions(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	gitCmd := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	gitCmd("init", "-q")
+	write("go.mod", "module example.com/shop\n\ngo 1.21\n")
+	write("shop.go", apiDiffBase)
+	write("internal/db/db.go", "package db\n\nfunc Open() {}\n")
+	write("testdata/broken/broken.go", "package broken\n\nfunc {\n")
+	write("_tools/tools.go", "package tools\n\nfunc {\n")
+	gitCmd("add", "-A")
+	gitCmd("commit", "-q", "-m", "v1")
+	gitCmd("tag", "v1")
+	write("shop.go", apiDiffHead)
+	write("internal/db/db.go", "package db\n\nfunc Open(dsn string) {}\n")
+	gitCmd("commit", "-q", "-am", "v2")
+
+	diff, err := DiffAPIRevisions(context.Background(), dir, "v1", "HEAD")
+	require.NoError(t, err)
+	require.Equal(t, "v1", diff.Base)
+	require.Len(t, diff.Changes, 7)
+	require.Equal(t, "example.com/shop", diff.Changes[0].Package)
+
+	// The worktrees are removed once parsed
+	cmd := exec.Command("git", "worktree", "list")
+	cmd.Dir = dir
+	out, err := cmd.Output()
+	require.NoError(t, err)
+	require.Equal(t, 1, strings.Count(string(out), "\n"))
+
+	_, err = DiffAPIRevisions(context.Background(), dir, "missing", "HEAD")
+	require.Error(t, err)
+}
@@ -0,0 +1,192 @@
+package codesurgeon
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// CallRef is a call made from the body of a function or method.
+// The syntactic parsers resolve calls to functions of the same package, to functions of
+// imported packages and to methods called on the receiver. Other method calls (e.g. x.Do())
+// keep an empty Package. ParseModule resolves every static call through go/types.
+type CallRef struct {
+	Package  string   `json:"package,omitempty"`  // Import path of the package declaring the callee, empty when unresolved
+	Receiver string   `json:"receiver,omitempty"` // Receiver type of a called method (e.g. "*Server" or "Reader"), empty for functions
+	Name     string   `json:"name"`
+	Callee   string   `json:"callee"` // Called expression as written in the source, e.g. "s.store.Get"
+	Position Position `json:"position"`
+}
+
+// ReceiverTypeName returns the name of the receiver type without pointer or type arguments.
+func (c CallRef) ReceiverTypeName() string {
+	name := strings.TrimPrefix(c.Receiver, "*")
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+// callScope is what the call extraction knows about the function being parsed.
+type callScope struct {
+	fset     *token.FileSet
+	pkg      Package
+	pkgPath  string            // Import path of the package being parsed, or its name when unknown
+	imports  map[string]string // Import path by name used in the file
+	recvName string            // Name of the receiver variable of a method
+	recvType string            // Receiver type of a method, e.g. "*Server"
+}
+
+// newCallScope returns the callScope of the functions declared in file.
+func newCallScope(fset *token.FileSet, file *ast.File, pkg Package, pkgPath string) callScope {
+	imports := make(map[string]string)
+	for _, spec := range file.Imports {
+		importPath, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := importName(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if name == "_" || name == "." {
+			continue
+		}
+		imports[name] = importPath
+	}
+	return callScope{fset: fset, pkg: pkg, pkgPath: pkgPath, imports: imports}
+}
+
+// importName guesses the name of a package from its import path like goimports does, skipping
+// major version suffixes such as "/v2" or gopkg.in's ".v3", the "go-" prefix and what follows the
+// first character that can't be in an identifier, e.g. "foo" for "github.com/x/go-foo" or "foo-go".
+func importName(importPath string) string {
+	base := path.Base(importPath)
+	if isMajorVersion(base) {
+		base = path.Base(path.Dir(importPath))
+	}
+	if i := strings.LastIndex(base, ".v"); i > 0 && isMajorVersion(base[i+1:]) {
+		base = base[:i]
+	}
+	base = strings.TrimPrefix(base, "go-")
+	if i := strings.IndexFunc(base, func(r rune) bool {
+		return !(r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r))
+	}); i >= 0 {
+		base = base[:i]
+	}
+	return base
+}
+
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+// extractCalls returns the calls made in body, in source order.
+func extractCalls(body *ast.BlockStmt, scope callScope) []CallRef {
+	if body == nil {
+		return nil
+	}
+	var calls []CallRef
+	ast.Inspect(body, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		fun := ast.Unparen(call.Fun)
+		// Explicit instantiations of generic functions, e.g. Map[int, string](...)
+		switch f := fun.(type) {
+		case *ast.IndexExpr:
+			fun = f.X
+		case *ast.IndexListExpr:
+			fun = f.X
+		}
+
+		var ref CallRef
+		var resolved bool
+		if scope.pkg.typed != nil {
+			ref, resolved = resolveTypedCall(fun, scope.pkg.typed)
+		} else {
+			ref, resolved = resolveSyntacticCall(fun, scope)
+		}
+		if !resolved {
+			return true
+		}
+		ref.Callee = exprToString(fun)
+		ref.Position = newPosition(scope.fset, call)
+		calls = append(calls, ref)
+		return true
+	})
+	return calls
+}
+
+// resolveSyntacticCall resolves the callee of a call from the syntax alone.
+// Conversions and builtins are skipped when they can be recognized.
+func resolveSyntacticCall(fun ast.Expr, scope callScope) (CallRef, bool) {
+	switch f := fun.(type) {
+	case *ast.Ident:
+		if obj := types.Universe.Lookup(f.Name); obj != nil {
+			// Builtins such as len or append and conversions such as string(b)
+			return CallRef{}, false
+		}
+		if f.Obj != nil && (f.Obj.Kind == ast.Typ || f.Obj.Kind == ast.Var) {
+			// Conversions to local types and calls of function values
+			return CallRef{}, false
+		}
+		return CallRef{Package: scope.pkgPath, Name: f.Name}, true
+	case *ast.SelectorExpr:
+		if x, ok := f.X.(*ast.Ident); ok {
+			if x.Obj == nil {
+				if importPath, ok := scope.imports[x.Name]; ok {
+					return CallRef{Package: importPath, Name: f.Sel.Name}, true
+				}
+			}
+			if scope.recvName != "" && x.Name == scope.recvName {
+				return CallRef{Package: scope.pkgPath, Receiver: scope.recvType, Name: f.Sel.Name}, true
+			}
+		}
+		return CallRef{Name: f.Sel.Name}, true
+	}
+	// Function literals and calls of returned functions
+	return CallRef{}, false
+}
+
+// resolveTypedCall resolves the callee of a call with the type information of the package.
+// Only calls of declared functions and methods are kept, calls of function values are skipped.
+func resolveTypedCall(fun ast.Expr, typed *typedPackage) (CallRef, bool) {
+	var obj types.Object
+	switch f := fun.(type) {
+	case *ast.Ident:
+		obj = typed.info.Uses[f]
+	case *ast.SelectorExpr:
+		if sel, ok := typed.info.Selections[f]; ok {
+			if sel.Kind() == types.FieldVal {
+				return CallRef{}, false
+			}
+			obj = sel.Obj()
+		} else {
+			obj = typed.info.Uses[f.Sel] // Qualified identifier, e.g. fmt.Println
+		}
+	}
+	fn, ok := obj.(*types.Func)
+	if !ok {
+		return CallRef{}, false
+	}
+	fn = fn.Origin()
+
+	ref := CallRef{Name: fn.Name()}
+	if fn.Pkg() != nil {
+		ref.Package = fn.Pkg().Path()
+	}
+	if recv := fn.Type().(*types.Signature).Recv(); recv != nil {
+		ref.Receiver = types.TypeString(recv.Type(), func(*types.Package) string { return "" })
+	}
+	return ref, true
+}
@@ -0,0 +1,165 @@
+package codesurgeon
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FragmentError is the failure of a fragment of a change set, or of a whole file.
+type FragmentError struct {
+	File     string `json:"file"`
+	Fragment int    `json:"fragment"` // Index of the fragment among the fragments of File, in the order of the changes. -1 when the file itself failed, e.g. it couldn't be written.
+	Err      error  `json:"-"`
+}
+
+func (e FragmentError) Error() string {
+	if e.Fragment < 0 {
+		return fmt.Sprintf("%s: %v", e.File, e.Err)
+	}
+	return fmt.Sprintf("%s: fragment %d: %v", e.File, e.Fragment, e.Err)
+}
+
+func (e FragmentError) Unwrap() error {
+	return e.Err
+}
+
+// ChangeSetError is returned by ApplyChangeSet when it fails, listing every failure. No file was
+// modified then.
+type ChangeSetError struct {
+	Failures []FragmentError `json:"failures"`
+}
+
+func (e *ChangeSetError) Error() string {
+	lines := make([]string, 0, len(e.Failures)+1)
+	lines = append(lines, fmt.Sprintf("change set failed with %d errors, no file was modified:", len(e.Failures)))
+	for _, f := range e.Failures {
+		lines = append(lines, "  "+f.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ApplyChangeSet applies changes as a transaction. The new content of every file is computed and
+// validated first, as with PreviewFileChanges, then written to temporary files renamed over the
+// files. Nothing is written when any fragment fails, and the files already renamed are restored
+// when a file can't be written. The error is a *ChangeSetError listing every failure.
+// It returns the previews of the files, as applied.
+func ApplyChangeSet(changes []FileChange) ([]FilePreview, error) {
+	previews, err := PreviewFileChanges(changes)
+	if err != nil {
+		return nil, err
+	}
+	if err := commitPreviews(previews); err != nil {
+		return nil, err
+	}
+	return previews, nil
+}
+
+// stagedFile is a file of a change set being committed.
+type stagedFile struct {
+	preview   FilePreview
+	mode      fs.FileMode
+	tmp       string // Temporary file with the new content, until renamed
+	committed bool   // The temporary file was renamed over the file
+}
+
+// commitPreviews writes the new content of previews with temporary files renamed over the files,
+// rolling back everything on failure.
+func commitPreviews(previews []FilePreview) error {
+	var staged []*stagedFile
+	var createdDirs []string
+	rollback := func() {
+		for i := len(staged) - 1; i >= 0; i-- {
+			f := staged[i]
+			if f.tmp != "" {
+				os.Remove(f.tmp)
+			}
+			if !f.committed {
+				continue
+			}
+			if f.preview.IsNew {
+				os.Remove(f.preview.File)
+			} else if tmp, err := writeTempFile(f.preview.File, []byte(f.preview.OldContent), f.mode); err == nil {
+				if err := os.Rename(tmp, f.preview.File); err != nil {
+					os.Remove(tmp)
+				}
+			}
+		}
+		// Children first, only the empty ones are removed
+		for i := len(createdDirs) - 1; i >= 0; i-- {
+			os.Remove(createdDirs[i])
+		}
+	}
+	fail := func(file string, err error) error {
+		rollback()
+		return &ChangeSetError{Failures: []FragmentError{{File: file, Fragment: -1, Err: err}}}
+	}
+
+	for _, p := range previews {
+		if p.Diff == "" && !p.IsNew {
+			continue
+		}
+		f := &stagedFile{preview: p, mode: 0644}
+		if info, err := os.Stat(p.File); err == nil {
+			f.mode = info.Mode().Perm()
+		}
+		dirs, err := mkdirAll(filepath.Dir(p.File))
+		createdDirs = append(createdDirs, dirs...)
+		if err != nil {
+			return fail(p.File, err)
+		}
+		staged = append(staged, f)
+		if f.tmp, err = writeTempFile(p.File, []byte(p.NewContent), f.mode); err != nil {
+			return fail(p.File, err)
+		}
+	}
+	for _, f := range staged {
+		if err := os.Rename(f.tmp, f.preview.File); err != nil {
+			return fail(f.preview.File, err)
+		}
+		f.tmp = ""
+		f.committed = true
+	}
+	return nil
+}
+
+// writeTempFile writes content to a new temporary file next to file, so that it can be renamed
+// over it, and returns its path.
+func writeTempFile(file string, content []byte, mode fs.FileMode) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Chmod(tmp, mode); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return tmp, nil
+}
+
+// mkdirAll is os.MkdirAll returning the directories it created, parents first.
+func mkdirAll(dir string) ([]string, error) {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append([]string{d}, missing...)
+		if filepath.Dir(d) == d {
+			break
+		}
+	}
+	return missing, os.MkdirAll(dir, 0755)
+}
@@ -0,0 +1,110 @@
+package codesurgeon
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyChangeSet(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a.go")
+	second := filepath.Join(dir, "b.go")
+	created := filepath.Join(dir, "pkg", "sub", "c.go")
+	original := "package main\n\nfunc A() {}\n"
+	require.NoError(t, os.WriteFile(first, []byte(original), 0600))
+	require.NoError(t, os.WriteFile(second, []byte(original), 0644))
+
+	// Fragments failing in two files: nothing is written
+	_, err := ApplyChangeSet([]FileChange{
+		{PackageName: "main", File: first, Fragments: []CodeFragment{{Content: "func B() {}"}, {Content: "func C( {"}}},
+		{PackageName: "sub", File: created, Fragments: []CodeFragment{{Content: "func D() {}"}}},
+		{PackageName: "main", File: second, Fragments: []CodeFragment{{Content: "type T struct {"}}},
+	})
+	var changeSetErr *ChangeSetError
+	require.True(t, errors.As(err, &changeSetErr))
+	require.Len(t, changeSetErr.Failures, 2)
+	require.Equal(t, first, changeSetErr.Failures[0].File)
+	require.Equal(t, 1, changeSetErr.Failures[0].Fragment)
+	require.Equal(t, second, changeSetErr.Failures[1].File)
+	require.Equal(t, 0, changeSetErr.Failures[1].Fragment)
+	require.Contains(t, err.Error(), "change set failed with 2 errors, no file was modified:\n  "+first+": fragment 1: failed to parse fragment")
+	for _, file := range []string{first, second} {
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Equal(t, original, string(content))
+	}
+	require.NoDirExists(t, filepath.Join(dir, "pkg"))
+
+	previews, err := ApplyChangeSet([]FileChange{
+		{PackageName: "main", File: first, Fragments: []CodeFragment{{Content: "func B() {}"}}},
+		{PackageName: "sub", File: created, Fragments: []CodeFragment{{Content: "func D() {}"}}},
+		{PackageName: "main", File: second, Fragments: []CodeFragment{{Content: "func A() {}"}}},
+	})
+	require.NoError(t, err)
+	require.Len(t, previews, 3)
+	content, err := os.ReadFile(first)
+	require.NoError(t, err)
+	require.Equal(t, "package main\n\nfunc A() {}\n\nfunc B() {}\n", string(content))
+	info, err := os.Stat(first)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	content, err = os.ReadFile(created)
+	require.NoError(t, err)
+	require.Equal(t, "package sub\n\nfunc D() {}\n", string(content))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 3, "no temporary file is left")
+}
+
+func TestFileChangeJSON(t *testing.T) {
+	// The fields of the ApplyFileChanges RPC and the applyFileChanges MCP tool
+	var changes []FileChange
+	require.NoError(t, json.Unmarshal([]byte(`[{"package_name": "main", "file": "main.go", "fragments": [
+		{"content": "func Hello() {}", "overwrite": true},
+		{"operation": "remove", "selectors": [{"name": "Bye"}, {"receiver": "Server", "name": "Close"}]}
+	]}]`), &changes))
+	require.Equal(t, []FileChange{{
+		PackageName: "main",
+		File:        "main.go",
+		Fragments: []CodeFragment{
+			{Content: "func Hello() {}", Overwrite: true},
+			{Operation: OperationRemove, Selectors: []DeclSelector{{Name: "Bye"}, {Receiver: "Server", Name: "Close"}}},
+		},
+	}}, changes)
+}
+
+func TestCommitPreviewsRollback(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.go")
+	original := "package main\n"
+	require.NoError(t, os.WriteFile(file, []byte(original), 0644))
+	// A file can't be renamed over a directory
+	conflict := filepath.Join(dir, "b.go")
+	require.NoError(t, os.MkdirAll(filepath.Join(conflict, "x"), 0755))
+	created := filepath.Join(dir, "new", "c.go")
+
+	err := commitPreviews([]FilePreview{
+		{File: file, OldContent: original, NewContent: "package main\n\nfunc A() {}\n", Diff: "-"},
+		{File: created, NewContent: "package new\n", IsNew: true},
+		{File: conflict, NewContent: "package main\n", Diff: "-"},
+	})
+	var changeSetErr *ChangeSetError
+	require.True(t, errors.As(err, &changeSetErr))
+	require.Equal(t, conflict, changeSetErr.Failures[0].File)
+	require.Equal(t, -1, changeSetErr.Failures[0].Fragment)
+
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.Equal(t, original, string(content))
+	require.NoFileExists(t, created)
+	require.NoDirExists(t, filepath.Join(dir, "new"))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "no temporary file is left")
+}
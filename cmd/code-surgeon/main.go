@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -114,7 +117,7 @@ func main() {
 					&cli.StringFlag{
 						Name:  "format",
 						Value: "llm",
-						Usage: "format to print the parsed information: llm, text_short, test_long, json",
+						Usage: "format to print the parsed information: llm, text_short, test_long, json, jsonl (one record per entity)",
 					},
 					&cli.BoolFlag{
 						Name:  "plain-structs",
@@ -156,35 +159,209 @@ func main() {
 						Usage: "print tags",
 						Value: true,
 					},
+					&cli.StringSliceFlag{
+						Name:  "tag-keys",
+						Usage: "only print these struct tag keys, e.g. json,db (llm and text_long formats, the llm format prints no tags without them unless --tags is given)",
+					},
 					&cli.StringSliceFlag{
 						Name:  "ignore-rule",
 						Usage: "ignore files or directories that match the rule. ",
 					},
+					&cli.BoolFlag{
+						Name:  "typed",
+						Usage: "type-check the packages to resolve import paths and builtin types (slower, path must be a directory)",
+					},
+					&cli.StringFlag{
+						Name:  "goos",
+						Usage: "only parse the files go build would compile for this operating system",
+					},
+					&cli.StringFlag{
+						Name:  "goarch",
+						Usage: "only parse the files go build would compile for this architecture",
+					},
+					&cli.StringSliceFlag{
+						Name:  "build-tags",
+						Usage: "only parse the files go build would compile with these build tags",
+					},
+					&cli.BoolFlag{
+						Name:  "tests",
+						Usage: "keep the _test.go files when evaluating build constraints (--goos, --goarch or --build-tags)",
+					},
+					&cli.BoolFlag{
+						Name:  "cache",
+						Usage: "reuse the parsing of unchanged packages from the cache in " + codesurgeon.DefaultParseCacheDir + " at the root of the module",
+					},
+					&cli.BoolFlag{
+						Name:  "tolerant",
+						Usage: "print what can be parsed from files with syntax errors and report the errors as diagnostics",
+					},
 				},
 				Action: func(cCtx *cli.Context) error {
 					path := cCtx.String("path") // Get the 'path' argument
 
 					ignores := cCtx.StringSlice("ignore-rule")
 
-					if cCtx.Bool("recursive") {
-						// ParseDirectoryRecursive
-						parsed, err := codesurgeon.ParseDirectoryRecursive(path)
+					opts := codesurgeon.ParseOptions{
+						Dir:       path,
+						Tests:     cCtx.Bool("tests"),
+						GOOS:      cCtx.String("goos"),
+						GOARCH:    cCtx.String("goarch"),
+						BuildTags: cCtx.StringSlice("build-tags"),
+						Tolerant:  cCtx.Bool("tolerant"),
+						// Without build flags every file is parsed, whatever its build constraints
+						AllFiles: !(cCtx.IsSet("goos") || cCtx.IsSet("goarch") || cCtx.IsSet("build-tags") || cCtx.IsSet("tests")),
+					}
+					if cCtx.Bool("cache") {
+						cache, err := codesurgeon.OpenModuleParseCache(path)
 						if err != nil {
-							log.Fatal().Err(err).Msg("Failed to parse directory")
+							log.Fatal().Err(err).Msg("Failed to open parse cache")
 						}
+						opts.Cache = cache
+					}
 
-						fmt.Println(codesurgeon.PrettyPrint(parsed, cCtx.String("format"), ignores, cCtx.Bool("plain-structs"), cCtx.Bool("fields-plain-structs"), cCtx.Bool("structs-with-method"), cCtx.Bool("fields-structs-with-method"), cCtx.Bool("methods"), cCtx.Bool("functions"), cCtx.Bool("tags"), cCtx.Bool("comments")))
-
+					report := func(info *codesurgeon.ParsedInfo) {
+						for _, e := range info.Errors {
+							log.Warn().Str("directory", e.Directory).Msg(e.Message)
+						}
+						for _, d := range info.Diagnostics {
+							log.Warn().Str("severity", d.Severity).Str("position", d.Position.String()).Msg(d.Message)
+						}
+					}
+					jsonl := cCtx.String("format") == "jsonl"
+					out := bufio.NewWriter(os.Stdout)
+
+					var parsed []*codesurgeon.ParsedInfo
+					if cCtx.Bool("typed") {
+						pattern := "."
+						if cCtx.Bool("recursive") {
+							pattern = "./..."
+						}
+						infos, err := codesurgeon.ParseModule(cCtx.Context, pattern, opts)
+						if err != nil {
+							log.Fatal().Err(err).Msg("Failed to parse module")
+						}
+						parsed = infos
+					} else if cCtx.Bool("recursive") {
+						err := codesurgeon.WalkPackages(cCtx.Context, path, opts, func(info *codesurgeon.ParsedInfo) error {
+							if jsonl {
+								// Written package by package as they're parsed rather than built in memory
+								report(info)
+								return codesurgeon.WriteJSONL(out, info)
+							}
+							parsed = append(parsed, info)
+							return nil
+						})
+						if err != nil {
+							log.Fatal().Err(err).Msg("Failed to parse directory")
+						}
+						// Resolve embedded types across all the packages that were parsed
+						codesurgeon.ComputeMethodSets(parsed...)
 					} else {
-						parsed, err := codesurgeon.ParseDirectoryWithFilter(path, nil)
+						info, err := codesurgeon.ParseDirectoryWithOptions(path, opts)
 						if err != nil {
 							log.Fatal().Err(err).Msg("Failed to parse directory")
 						}
-						fmt.Println(codesurgeon.PrettyPrint([]*codesurgeon.ParsedInfo{parsed}, cCtx.String("format"), ignores, cCtx.Bool("plain-structs"), cCtx.Bool("fields-plain-structs"), cCtx.Bool("structs-with-method"), cCtx.Bool("fields-structs-with-method"), cCtx.Bool("methods"), cCtx.Bool("functions"), cCtx.Bool("tags"), cCtx.Bool("comments")))
+						parsed = []*codesurgeon.ParsedInfo{info}
+					}
+					for _, info := range parsed {
+						report(info)
+					}
+					if jsonl {
+						if err := codesurgeon.WriteJSONL(out, parsed...); err != nil {
+							return err
+						}
+						return out.Flush()
+					}
+					tagKeys := cCtx.StringSlice("tag-keys")
+					if cCtx.String("format") == "llm" && len(tagKeys) == 0 && cCtx.IsSet("tags") && cCtx.Bool("tags") {
+						// The llm format only prints tags when asked for
+						tagKeys = []string{codesurgeon.AllTagKeys}
+					}
+					fmt.Println(codesurgeon.PrettyPrint(parsed, cCtx.String("format"), ignores, cCtx.Bool("plain-structs"), cCtx.Bool("fields-plain-structs"), cCtx.Bool("structs-with-method"), cCtx.Bool("fields-structs-with-method"), cCtx.Bool("methods"), cCtx.Bool("functions"), cCtx.Bool("tags"), cCtx.Bool("comments"), tagKeys...))
+					return nil
+				},
+			},
+			{
+				Name:  "api-diff",
+				Usage: "report the changes of the exported API of a module between two git revisions",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "base",
+						Usage:    "git revision to compare from, e.g. a tag",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "head",
+						Usage: "git revision to compare to",
+						Value: "HEAD",
+					},
+					&cli.StringFlag{
+						Name:  "path",
+						Usage: "path to the module inside the git repository",
+						Value: ".",
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "format to print the changes: text, json, markdown",
+						Value: "text",
+					},
+				},
+				Action: func(cCtx *cli.Context) error {
+					diff, err := codesurgeon.DiffAPIRevisions(cCtx.Context, cCtx.String("path"), cCtx.String("base"), cCtx.String("head"))
+					if err != nil {
+						return err
+					}
+					out, err := codesurgeon.FormatAPIDiff(diff, cCtx.String("format"))
+					if err != nil {
+						return err
 					}
+					fmt.Print(out)
 					return nil
 				},
 			},
+			{
+				Name:  "apply-changes",
+				Usage: "insert, overwrite or remove declarations in go files, or print the diff of the changes with --dry-run",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "changes",
+						Aliases:  []string{"c"},
+						Usage:    "path to a JSON file with a list of file changes ({\"package_name\", \"file\", \"fragments\": [{\"content\", \"overwrite\", \"operation\", \"selectors\"}]}), - for stdin",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "print the unified diff of the changes without writing the files",
+					},
+				},
+				Action: func(cCtx *cli.Context) error {
+					var content []byte
+					var err error
+					if path := cCtx.String("changes"); path == "-" {
+						content, err = io.ReadAll(os.Stdin)
+					} else {
+						content, err = os.ReadFile(path)
+					}
+					if err != nil {
+						return err
+					}
+					var changes []codesurgeon.FileChange
+					if err := json.Unmarshal(content, &changes); err != nil {
+						return fmt.Errorf("error decoding changes: %w", err)
+					}
+
+					if cCtx.Bool("dry-run") {
+						previews, err := codesurgeon.PreviewFileChanges(changes)
+						if err != nil {
+							return err
+						}
+						fmt.Print(codesurgeon.FormatPreviews(previews))
+						return nil
+					}
+					_, err = codesurgeon.ApplyChangeSet(changes)
+					return err
+				},
+			},
 			{
 				Name:  "document-functions",
 				Usage: "generate AI documentation for golang code on a  path",
@@ -425,9 +602,68 @@ func main() {
 						Name:  "recursive",
 						Value: false,
 					},
+					&cli.BoolFlag{
+						Name:  "cache",
+						Usage: "reuse the parsing of unchanged packages from the cache in " + codesurgeon.DefaultParseCacheDir + " at the root of the module",
+					},
 				},
 				Action: func(cCtx *cli.Context) error {
-					return neo4j2.ToNeo4j(cCtx.Context, cCtx.String("path"), cCtx.Bool("deep"), myEnv, cCtx.Bool("recursive"))
+					return neo4j2.ToNeo4jWithOptions(cCtx.Context, cCtx.String("path"), myEnv, neo4j2.ToNeo4jOptions{
+						Deep:      cCtx.Bool("deep"),
+						Recursive: cCtx.Bool("recursive"),
+						UseCache:  cCtx.Bool("cache"),
+					})
+				},
+			},
+			{
+				Name:  "cache",
+				Usage: "inspect or clear the parse cache of a module",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "stats",
+						Usage: "print the number and size of the entries of the parse cache",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "path",
+								Usage: "path inside the module",
+								Value: ".",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							cache, err := codesurgeon.OpenModuleParseCache(cCtx.String("path"))
+							if err != nil {
+								return err
+							}
+							stats, err := cache.Stats()
+							if err != nil {
+								return err
+							}
+							fmt.Printf("dir: %s\nentries: %d\nsize: %d bytes\n", stats.Dir, stats.Entries, stats.Size)
+							return nil
+						},
+					},
+					{
+						Name:  "clear",
+						Usage: "remove every entry of the parse cache",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "path",
+								Usage: "path inside the module",
+								Value: ".",
+							},
+						},
+						Action: func(cCtx *cli.Context) error {
+							cache, err := codesurgeon.OpenModuleParseCache(cCtx.String("path"))
+							if err != nil {
+								return err
+							}
+							if err := cache.Clear(); err != nil {
+								return err
+							}
+							fmt.Printf("cleared %s\n", cache.Dir())
+							return nil
+						},
+					},
 				},
 			},
 			{
@@ -51,6 +51,8 @@ func (w *MCPServer) registerTools() {
 	w.addExecuteNeo4jQueryTool()
 	w.addAskQuestionsTool()
 	w.addGetNeo4jSchemaTool()
+	w.addParseCodebaseTool()
+	w.addApplyFileChangesTool()
 }
 
 func (w *MCPServer) addAskQuestionsTool() {
@@ -241,6 +243,85 @@ func (w *MCPServer) addGetNeo4jSchemaTool() {
 	})
 }
 
+// Adds a tool to parse Go code, including files that are not written to disk yet.
+func (w *MCPServer) addParseCodebaseTool() {
+	tool := mcp.NewTool("parseCodebase",
+		mcp.WithDescription("Parses the Go packages of a directory and lists their structs, interfaces, functions and methods."),
+		mcp.WithString("path", mcp.Description("The directory to parse, recursively."), mcp.Required()),
+		mcp.WithString("overlay", mcp.Description("A JSON object mapping file paths to contents that replace or add files before parsing, e.g. code about to be written.")),
+		mcp.WithString("format", mcp.Description("The output format: text_short (default), text_long, llm, json, jsonl or grepindex.")),
+	)
+	w.server.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.Params.Arguments
+		path, _ := args["path"].(string)
+		format, _ := args["format"].(string)
+		var overlay map[string]string
+		if overlayStr, _ := args["overlay"].(string); overlayStr != "" {
+			if err := json.Unmarshal([]byte(overlayStr), &overlay); err != nil {
+				return toolError("Error parsing overlay JSON: " + err.Error()), nil
+			}
+		}
+
+		client := apiconnect.NewGptServiceClient(http.DefaultClient, CODE_SURGEON_ADDRESS)
+		response, err := client.ParseCodebase(ctx, connect.NewRequest(&api.ParseCodebaseRequest{
+			Path:                    path,
+			Recursive:               true,
+			Format:                  format,
+			PlainStructs:            true,
+			FieldsPlainStructs:      true,
+			StructsWithMethod:       true,
+			FieldsStructsWithMethod: true,
+			Methods:                 true,
+			Functions:               true,
+			Comments:                true,
+			Tags:                    true,
+			Overlay:                 overlay,
+		}))
+		if err != nil {
+			return toolError("Error parsing codebase: " + err.Error()), nil
+		}
+		return toolSuccess([]mcp.TextContent{{Type: "text", Text: response.Msg.ParsedInfo}}), nil
+	})
+}
+
+// Adds a tool to insert code in Go files, previewing the diff first by default.
+func (w *MCPServer) addApplyFileChangesTool() {
+	tool := mcp.NewTool("applyFileChanges",
+		mcp.WithDescription("Inserts, overwrites or removes Go declarations in files and returns the unified diff of each file. With dryRun (the default) nothing is written, so that the diff can be approved first."),
+		mcp.WithString("changes", mcp.Description(`A JSON array of file changes, e.g. [{"package_name": "main", "file": "main.go", "fragments": [{"content": "func Hello() {}", "overwrite": true}, {"operation": "remove", "selectors": [{"name": "Bye"}, {"receiver": "Server", "name": "Close"}]}]}].`), mcp.Required()),
+		mcp.WithBoolean("dryRun", mcp.Description("Only return the diffs, without writing the files."), mcp.DefaultBool(true)),
+	)
+	w.server.AddTool(tool, func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		args := request.Params.Arguments
+		changesStr, _ := args["changes"].(string)
+		dryRun, ok := args["dryRun"].(bool)
+		if !ok {
+			dryRun = true
+		}
+		var changes []*api.FileChange
+		if err := json.Unmarshal([]byte(changesStr), &changes); err != nil {
+			return toolError("Error parsing changes JSON: " + err.Error()), nil
+		}
+
+		client := apiconnect.NewGptServiceClient(http.DefaultClient, CODE_SURGEON_ADDRESS)
+		response, err := client.ApplyFileChanges(ctx, connect.NewRequest(&api.ApplyFileChangesRequest{
+			Changes: changes,
+			DryRun:  dryRun,
+		}))
+		if err != nil {
+			return toolError("Error applying file changes: " + err.Error()), nil
+		}
+		var sb strings.Builder
+		for _, f := range response.Msg.Files {
+			sb.WriteString(f.Diff)
+		}
+		if sb.Len() == 0 {
+			sb.WriteString("No changes.")
+		}
+		return toolSuccess([]mcp.TextContent{{Type: "text", Text: sb.String()}}), nil
+	})
+}
+
 // Helper to build a tool success response.
 func toolSuccess(contents []mcp.TextContent) *mcp.CallToolResult {
 	var iface []interface{}
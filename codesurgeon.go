@@ -6,18 +6,16 @@ import (
 	"fmt"
 	"go/ast"
 	"go/format"
-	"go/parser"
-	"go/printer"
 	"go/token"
 	"io/fs"
 	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"text/template"
 
-	"golang.org/x/tools/go/ast/astutil"
 	"golang.org/x/tools/imports"
 )
 
@@ -25,80 +23,42 @@ var STATICFS, _ = fs.Sub(FS, "api")
 
 type (
 	FileChange struct {
-		PackageName string
-		File        string
-		Fragments   []CodeFragment
+		PackageName string         `json:"package_name"`
+		File        string         `json:"file"`
+		Fragments   []CodeFragment `json:"fragments"`
 	}
 
 	CodeFragment struct {
-		Content   string
-		Overwrite bool
+		Content   string         `json:"content"`
+		Overwrite bool           `json:"overwrite"`
+		Operation string         `json:"operation,omitempty"` // OperationUpsert (the default when empty) or OperationRemove
+		Selectors []DeclSelector `json:"selectors,omitempty"` // Declarations removed by OperationRemove
 	}
 )
 
-func ApplyFileChanges(changes []FileChange) error {
-	// Group changes by file
-	implementationsMap := make(map[string][]CodeFragment)
-	for _, change := range changes {
-		implementationsMap[change.File] = change.Fragments
-		// mkdir -p
-		if err := os.MkdirAll(filepath.Dir(change.File), 0755); err != nil {
-			return fmt.Errorf("Failed to create directory: %v", err)
-		}
-		// if file does not exist, create it
-		if _, err := os.Stat(change.File); os.IsNotExist(err) {
-			if f, err := os.Create(change.File); err != nil {
-				return fmt.Errorf("Failed to create file: %v", err)
-			} else {
-				f.Write([]byte("package " + change.PackageName + "\n"))
-				defer f.Close()
-			}
-		}
-	}
+// Operations of a CodeFragment.
+const (
+	OperationUpsert = "upsert" // Insert the declarations of Content, replacing the existing ones with Overwrite
+	OperationRemove = "remove" // Remove the declarations of Selectors
+)
 
-	return InsertCodeFragments(implementationsMap)
+// ApplyFileChanges applies changes as a transaction with ApplyChangeSet: files that don't exist
+// are created with the package clause of their PackageName, and nothing is written when any
+// fragment fails. The error is a *ChangeSetError listing every failure.
+func ApplyFileChanges(changes []FileChange) error {
+	_, err := ApplyChangeSet(changes)
+	return err
 }
 
+// InsertCodeFragments applies the fragments of each file of implementationsMap as a transaction,
+// like ApplyFileChanges, files that don't exist being created in package main.
+// The error is a *ChangeSetError listing every failure.
 func InsertCodeFragments(implementationsMap map[string][]CodeFragment) error {
-	// Apply changes to each file
-	for file, fragments := range implementationsMap {
-		fset := token.NewFileSet()
-		// if file does not exist, create it
-		if _, err := os.Stat(file); os.IsNotExist(err) {
-			if f, err := os.Create(file); err != nil {
-				return fmt.Errorf("Failed to create file: %v", err)
-			} else {
-				f.Write([]byte("package main\n"))
-				defer f.Close()
-			}
-		}
-		node, err := parser.ParseFile(fset, file, nil, parser.AllErrors|parser.ParseComments)
-
-		if err != nil {
-			fmt.Printf("Failed to parse file: %v\n", err)
-			continue
-		}
-
-		// // Process each change separately
-		for _, fragment := range fragments {
-			decls, err := parseDeclarationsFromCodeFrament(fragment)
-			if err != nil {
-				fmt.Printf("Failed to parse change: %v\n", err)
-				continue
-			}
-
-			for _, decl := range decls {
-				upsertDeclaration(node, decl, fragment.Overwrite)
-			}
-		}
-
-		err = writeChangesToFile(file, fset, node)
-		if err != nil {
-			fmt.Printf("Failed to write modified file: %v\n", err)
-			return err
-		}
+	previews, err := PreviewCodeFragments(implementationsMap)
+	if err != nil {
+		return err
 	}
-	return nil
+	return commitPreviews(previews)
 }
 
 // MustRenderTemplate is a helper function to render a template with the given data.
@@ -165,15 +125,7 @@ func FormatCodeAndFixImports(filePath string) error {
 }
 
 func parseDeclarationsFromCodeFrament(f CodeFragment) ([]ast.Decl, error) {
-	code := strings.TrimSpace(f.Content)
-	// check if no package is defined
-	if !strings.HasPrefix(code, "package") {
-		code = "package main\n\n" + code
-
-	}
-
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, "", code, parser.AllErrors|parser.ParseComments)
+	_, file, _, err := parseCodeFragment(f)
 	if err != nil {
 		return nil, err
 	}
@@ -181,131 +133,69 @@ func parseDeclarationsFromCodeFrament(f CodeFragment) ([]ast.Decl, error) {
 	return file.Decls, nil
 }
 
-func upsertDeclaration(file *ast.File, newDecl ast.Decl, overwrite bool) {
-	shouldAppend := true
-
-	astutil.Apply(file, func(c *astutil.Cursor) bool {
-		decl, ok := c.Node().(ast.Decl)
-		if !ok {
-			return true
-		}
-
-		switch existing := decl.(type) {
-		case *ast.GenDecl:
-			// Handle import declarations
-			if existing.Tok == token.IMPORT {
-				// If the new declaration is also an import, merge it
-				if newGenDecl, ok := newDecl.(*ast.GenDecl); ok && newGenDecl.Tok == token.IMPORT {
-					existing.Specs = append(existing.Specs, newGenDecl.Specs...)
-					shouldAppend = false
-					return false
-				}
-				return true
-			}
-
-			// Handle type declarations
-			if ts, ok := existing.Specs[0].(*ast.TypeSpec); ok {
-				if ts.Name.Name == getDeclName(newDecl) {
-					if overwrite {
-						c.Replace(newDecl)
-					}
-					shouldAppend = false
-					return false
-				}
-			}
-
-		case *ast.FuncDecl:
-			// Handle function declarations
-			newFunc, ok := newDecl.(*ast.FuncDecl)
-			if !ok {
-				return true
-			}
-
-			if existing.Name.Name == newFunc.Name.Name {
-				// fmt.Printf("newDecl\n%s", spew.Sdump(newDecl))   // TODO: wallace debug
-				// fmt.Printf("existing\n%s", spew.Sdump(existing)) // TODO: wallace debug
-				// fmt.Printf("newFunc\n%s", spew.Sdump(newFunc))   // TODO: wallace debug
-
-				existingRecv := getReceiverType(existing)
-				newRecv := getReceiverType(newFunc)
-				if existingRecv == newRecv {
-					// Add a default comment if none exists
-					// AddDocumentationToFunc(file, existing.Name.Name, fmt.Sprintf("%s auto-generated documentation", existing.Name.Name))
-					if overwrite {
-						c.Replace(newDecl)
-					}
-					shouldAppend = false
-					return false
-				}
-			}
+// findSpec returns the declaration of file of kind tok, e.g. token.CONST, with a spec declaring
+// one of names, and the index of the spec. It returns nil when there's none.
+func findSpec(file *ast.File, tok token.Token, names []string) (*ast.GenDecl, int) {
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != tok {
+			continue
 		}
-
-		return true
-	}, nil)
-
-	// If the new declaration is an import and should be appended
-	if shouldAppend {
-		if newGenDecl, ok := newDecl.(*ast.GenDecl); ok && newGenDecl.Tok == token.IMPORT {
-			// Try to insert with existing imports if any
-			for _, decl := range file.Decls {
-				if existingImport, ok := decl.(*ast.GenDecl); ok && existingImport.Tok == token.IMPORT {
-					existingImport.Specs = append(existingImport.Specs, newGenDecl.Specs...)
-					return
+		for i, spec := range gd.Specs {
+			for _, name := range specNames(spec) {
+				if slices.Contains(names, name) {
+					return gd, i
 				}
 			}
 		}
-		file.Decls = append(file.Decls, newDecl)
 	}
+	return nil, -1
 }
 
-func getReceiverType(funcDecl *ast.FuncDecl) string {
-	if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
-		if starExpr, ok := funcDecl.Recv.List[0].Type.(*ast.StarExpr); ok {
-			if ident, ok := starExpr.X.(*ast.Ident); ok {
-				return ident.Name
-			}
-		} else if ident, ok := funcDecl.Recv.List[0].Type.(*ast.Ident); ok {
-			return ident.Name
+// specNames returns the names declared by a type, const or var spec.
+func specNames(spec ast.Spec) []string {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return []string{s.Name.Name}
+	case *ast.ValueSpec:
+		names := make([]string, 0, len(s.Names))
+		for _, name := range s.Names {
+			names = append(names, name.Name)
 		}
+		return names
 	}
-	return ""
+	return nil
 }
 
-func getDeclName(decl ast.Decl) string {
-	if fd, ok := decl.(*ast.FuncDecl); ok {
-		return fd.Name.Name
-	}
-	if gd, ok := decl.(*ast.GenDecl); ok {
-		if ts, ok := gd.Specs[0].(*ast.TypeSpec); ok {
-			return ts.Name.Name
+// checkSpecReplacement fails when replacing existing with spec would drop names existing declares.
+func checkSpecReplacement(existing, spec ast.Spec) error {
+	names := specNames(spec)
+	for _, name := range specNames(existing) {
+		if !slices.Contains(names, name) {
+			return fmt.Errorf("cannot replace %s: it's declared together with %s", strings.Join(names, ", "), name)
 		}
 	}
-	return ""
-}
-
-func writeChangesToFile(filePath string, fset *token.FileSet, node ast.Node) error {
-	var buf bytes.Buffer
-	if err := printer.Fprint(&buf, fset, node); err != nil {
-		return err
-	}
-
-	// Apply gofmt to the generated code
-	formattedCode, err := format.Source(buf.Bytes())
-	if err != nil {
-		return err
-	}
-
-	// Write the formatted code to the file
-	return ioutil.WriteFile(filePath, formattedCode, 0644)
+	return nil
 }
 
-func renderModifiedNode(fset *token.FileSet, node ast.Node) (string, error) {
-	var buf bytes.Buffer
-	if err := printer.Fprint(&buf, fset, node); err != nil {
-		return "", err
+func getReceiverType(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv != nil && len(funcDecl.Recv.List) > 0 {
+		typ := funcDecl.Recv.List[0].Type
+		if starExpr, ok := typ.(*ast.StarExpr); ok {
+			typ = starExpr.X
+		}
+		// Generic receivers, e.g. List[T]
+		switch t := typ.(type) {
+		case *ast.IndexExpr:
+			typ = t.X
+		case *ast.IndexListExpr:
+			typ = t.X
+		}
+		if ident, ok := typ.(*ast.Ident); ok {
+			return ident.Name
+		}
 	}
-
-	return buf.String(), nil
+	return ""
 }
 
 func ToSnakeCase(s string) string {
@@ -1,11 +1,13 @@
 package codesurgeon
 
 import (
-	"bytes"
+	"fmt"
 	"go/ast"
+	"go/format"
 	"go/parser"
-	"go/printer"
 	"go/token"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/rs/zerolog/log"
@@ -29,11 +31,11 @@ func TestParseMultipleDeclarations(t *testing.T) {
 		t.Fatalf("Expected 3 declarations, got %d", len(decls))
 	}
 
-	if getDeclName(decls[0]) != "Struct1" || getDeclName(decls[1]) != "Struct2" {
+	if specNames(decls[0].(*ast.GenDecl).Specs[0])[0] != "Struct1" || specNames(decls[1].(*ast.GenDecl).Specs[0])[0] != "Struct2" {
 		t.Errorf("Type names do not match expected values")
 	}
 
-	if getDeclName(decls[2]) != "Function1" {
+	if decls[2].(*ast.FuncDecl).Name.Name != "Function1" {
 		t.Errorf("Function name does not match expected value")
 	}
 }
@@ -44,25 +46,19 @@ func TestReplaceOrAddDecl_dontoverwrite(t *testing.T) {
 	type Struct1 struct { Field1 string }
 	func Function1(param1 int) string { return "result" }
 	`
-	// Parse the original code
-	file := parseCode(t, originalCode)
-
 	// New declaration to replace the existing Struct1
 	newDeclCode := `package main; 
 	type Struct1 struct {
 		Field1 string; 
 		Field2 int
 	}`
-	newDecl := parseCode(t, newDeclCode).Decls[0]
 
 	// Replace or add the new declaration
-	upsertDeclaration(file, newDecl, false)
-
-	// Format the modified code
-	result := formatCode(t, file)
+	result, err := upsertCodeFragments("main.go", []byte(originalCode), []CodeFragment{{Content: newDeclCode}})
+	require.NoError(t, err)
 
 	// Verify that the new declaration was added correctly
-	structs := getMapStructsFieldsType(result)
+	structs := getMapStructsFieldsType(string(result))
 	require.Contains(t, structs, "Struct1", "Struct1 was not found")
 	require.Len(t, structs["Struct1"], 1, "Expected 1 fields in Struct1")
 	require.Contains(t, structs["Struct1"], "Field1", "Field1 not found in Struct1")
@@ -76,25 +72,19 @@ func TestReplaceOrAddDecl_overwrite(t *testing.T) {
 	type Struct1 struct { Field1 string }
 	func Function1(param1 int) string { return "result" }
 	`
-	// Parse the original code
-	file := parseCode(t, originalCode)
-
 	// New declaration to replace the existing Struct1
 	newDeclCode := `package main; 
 	type Struct1 struct {
 		Field1 string; 
 		Field2 int
 	}`
-	newDecl := parseCode(t, newDeclCode).Decls[0]
 
 	// Replace or add the new declaration
-	upsertDeclaration(file, newDecl, true)
-
-	// Format the modified code
-	result := formatCode(t, file)
+	result, err := upsertCodeFragments("main.go", []byte(originalCode), []CodeFragment{{Content: newDeclCode, Overwrite: true}})
+	require.NoError(t, err)
 
 	// Verify that the new declaration was added correctly
-	structs := getMapStructsFieldsType(result)
+	structs := getMapStructsFieldsType(string(result))
 	require.Contains(t, structs, "Struct1", "Struct1 was not found")
 	require.Len(t, structs["Struct1"], 2, "Expected 2 fields in Struct1")
 	require.Contains(t, structs["Struct1"], "Field1", "Field1 not found in Struct1")
@@ -103,34 +93,6 @@ func TestReplaceOrAddDecl_overwrite(t *testing.T) {
 	require.Equal(t, "int", structs["Struct1"]["Field2"], "Field2 should be of type int")
 }
 
-// Test for getTypeName
-func TestGetTypeName(t *testing.T) {
-	code := `type MyStruct struct { Field1 string }`
-	decls, err := parseDeclarationsFromCodeFrament(CodeFragment{Content: code})
-	if err != nil {
-		t.Fatalf("Failed to parse declaration: %v", err)
-	}
-
-	typeName := getDeclName(decls[0])
-	if typeName != "MyStruct" {
-		t.Errorf("Expected 'MyStruct', got '%s'", typeName)
-	}
-}
-
-// Test for getFuncName
-func TestGetFuncName(t *testing.T) {
-	code := `func MyFunction(param1 int) string { return "result" }`
-	decls, err := parseDeclarationsFromCodeFrament(CodeFragment{Content: code})
-	if err != nil {
-		t.Fatalf("Failed to parse declaration: %v", err)
-	}
-
-	funcName := getDeclName(decls[0])
-	if funcName != "MyFunction" {
-		t.Errorf("Expected 'MyFunction', got '%s'", funcName)
-	}
-}
-
 // Test for empty declarations in parseMultipleDeclarations
 func TestParseMultipleDeclarations_EmptyCode(t *testing.T) {
 	code := "" // Empty input
@@ -160,30 +122,18 @@ func TestReplaceOrAddDecl_AddNewDeclarationRepeatedName(t *testing.T) {
 	type ExistingStruct struct { Field1 string }
 	func (e *ExistingStruct) SomeMethod(arg1 string) {}
 	`
-	// Parse the original file
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, "", originalCode, parser.AllErrors)
-	if err != nil {
-		t.Fatalf("Failed to parse original code: %v", err)
-	}
-
 	// New declaration to add
 	newDeclCode := `func SomeMethod(arg1 string) {}`
-	decls, err := parseDeclarationsFromCodeFrament(CodeFragment{Content: newDeclCode})
-	if err != nil {
-		t.Fatalf("Failed to parse new declaration: %v", err)
-	}
 
 	// Add the new declaration
-	upsertDeclaration(file, decls[0], true)
-
-	rendered, err := renderModifiedNode(fset, file)
+	rendered, err := applyCodeFragment("main.go", []byte(originalCode), CodeFragment{Content: newDeclCode, Overwrite: true})
 	if err != nil {
-		panic(err)
+		t.Fatalf("Failed to add new declaration: %v", err)
 	}
 
-	fset = token.NewFileSet()
+	fset := token.NewFileSet()
 	file2, err := parser.ParseFile(fset, "", rendered, parser.AllErrors)
+	require.NoError(t, err)
 
 	countSomeMethod := 0
 	for _, decl := range file2.Decls {
@@ -205,30 +155,18 @@ func TestReplaceOrAddDecl_AddNewDeclaration(t *testing.T) {
 	package main
 	type ExistingStruct struct { Field1 string }
 	`
-	// Parse the original file
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, "", originalCode, parser.AllErrors)
-	if err != nil {
-		t.Fatalf("Failed to parse original code: %v", err)
-	}
-
 	// New declaration to add
 	newDeclCode := `type NewStruct struct { Field2 int }`
-	decls, err := parseDeclarationsFromCodeFrament(CodeFragment{Content: newDeclCode})
-	if err != nil {
-		t.Fatalf("Failed to parse new declaration: %v", err)
-	}
 
 	// Add the new declaration
-	upsertDeclaration(file, decls[0], true)
-
-	rendered, err := renderModifiedNode(fset, file)
+	rendered, err := applyCodeFragment("main.go", []byte(originalCode), CodeFragment{Content: newDeclCode, Overwrite: true})
 	if err != nil {
-		panic(err)
+		t.Fatalf("Failed to add new declaration: %v", err)
 	}
 
-	fset = token.NewFileSet()
+	fset := token.NewFileSet()
 	file2, err := parser.ParseFile(fset, "", rendered, parser.AllErrors)
+	require.NoError(t, err)
 	// Check if the new declaration was added
 	found := false
 	for _, decl := range file2.Decls {
@@ -254,13 +192,6 @@ func TestReplaceOrAddDecl_ComplexDeclarations(t *testing.T) {
 	type ExistingStruct struct { Field1 string }
 	func (e *ExistingStruct) ExistingMethod() {}
 	`
-	// Parse the original file
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, "", originalCode, parser.AllErrors)
-	if err != nil {
-		t.Fatalf("Failed to parse original code: %v", err)
-	}
-
 	// New complex declaration
 	newDeclCode := `
 	type ComplexStruct struct {
@@ -273,15 +204,13 @@ func TestReplaceOrAddDecl_ComplexDeclarations(t *testing.T) {
 	func (c *ComplexStruct) ComplexMethod(param1 int) string {
 		return "result"
 	}`
-	decls, err := parseDeclarationsFromCodeFrament(CodeFragment{Content: newDeclCode})
-	if err != nil {
-		t.Fatalf("Failed to parse complex declaration: %v", err)
-	}
 
 	// Add the complex declarations
-	for _, decl := range decls {
-		upsertDeclaration(file, decl, true)
+	rendered, err := upsertCodeFragments("main.go", []byte(originalCode), []CodeFragment{{Content: newDeclCode, Overwrite: true}})
+	if err != nil {
+		t.Fatalf("Failed to add complex declaration: %v", err)
 	}
+	file := parseCode(t, string(rendered))
 
 	// Check if ComplexStruct and ComplexMethod were added
 	foundStruct := false
@@ -310,27 +239,18 @@ func TestMultipleChangesInSingleRequest(t *testing.T) {
 	package main
 	type ExistingStruct struct { Field1 string }
 	`
-	// Parse the original file
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, "", originalCode, parser.AllErrors)
-	if err != nil {
-		t.Fatalf("Failed to parse original code: %v", err)
-	}
-
 	// Multiple declarations
 	newDeclCode := `
 	type NewStruct1 struct { FieldA string }
 	type NewStruct2 struct { FieldB int }
 	`
-	decls, err := parseDeclarationsFromCodeFrament(CodeFragment{Content: newDeclCode})
-	if err != nil {
-		t.Fatalf("Failed to parse new declarations: %v", err)
-	}
 
 	// Apply multiple changes
-	for _, decl := range decls {
-		upsertDeclaration(file, decl, true)
+	rendered, err := upsertCodeFragments("main.go", []byte(originalCode), []CodeFragment{{Content: newDeclCode, Overwrite: true}})
+	if err != nil {
+		t.Fatalf("Failed to apply new declarations: %v", err)
 	}
+	file := parseCode(t, string(rendered))
 
 	// Check if both NewStruct1 and NewStruct2 were added
 	foundStruct1 := false
@@ -373,13 +293,10 @@ type ExistingType struct {
 	Age int
 }
 `
-	file := parseCode(t, src)
-	newDecl := parseCode(t, newDeclSrc).Decls[0]
-
-	upsertDeclaration(file, newDecl, true)
+	result, err := upsertCodeFragments("main.go", []byte(src), []CodeFragment{{Content: newDeclSrc, Overwrite: true}})
+	require.NoError(t, err)
 
-	result := formatCode(t, file)
-	structs := getMapStructsFieldsType(result)
+	structs := getMapStructsFieldsType(string(result))
 	require.Len(t, structs, 1)
 	require.Contains(t, structs, "ExistingType")
 	require.Len(t, structs["ExistingType"], 3)
@@ -408,13 +325,10 @@ type ExistingType struct {
 	Age int
 }
 `
-	file := parseCode(t, src)
-	newDecl := parseCode(t, newDeclSrc).Decls[0]
+	result, err := upsertCodeFragments("main.go", []byte(src), []CodeFragment{{Content: newDeclSrc}})
+	require.NoError(t, err)
 
-	upsertDeclaration(file, newDecl, false)
-
-	result := formatCode(t, file)
-	structs := getMapStructsFieldsType(result)
+	structs := getMapStructsFieldsType(string(result))
 	require.Len(t, structs, 1)
 	require.Contains(t, structs, "ExistingType")
 	require.Len(t, structs["ExistingType"], 1)
@@ -437,13 +351,9 @@ func NewFunction() {
 	fmt.Println("Hello from NewFunction")
 }
 `
-	file := parseCode(t, src)
-	newDecl := parseCode(t, newDeclSrc).Decls[0]
-
-	upsertDeclaration(file, newDecl, false)
-
-	result := formatCode(t, file)
-	require.Contains(t, result, "func NewFunction()")
+	result, err := upsertCodeFragments("main.go", []byte(src), []CodeFragment{{Content: newDeclSrc}})
+	require.NoError(t, err)
+	require.Contains(t, string(result), "func NewFunction()")
 }
 
 // Test replacing an existing function when overwrite is true
@@ -464,14 +374,10 @@ func ExistingFunction() {
 	fmt.Println("Replaced Function")
 }
 `
-	file := parseCode(t, src)
-	newDecl := parseCode(t, newDeclSrc).Decls[0]
-
-	upsertDeclaration(file, newDecl, true)
-
-	result := formatCode(t, file)
-	require.Contains(t, result, "fmt.Println(\"Replaced Function\")")
-	require.NotContains(t, result, "fmt.Println(\"Original Function\")")
+	result, err := upsertCodeFragments("main.go", []byte(src), []CodeFragment{{Content: newDeclSrc, Overwrite: true}})
+	require.NoError(t, err)
+	require.Contains(t, string(result), "fmt.Println(\"Replaced Function\")")
+	require.NotContains(t, string(result), "fmt.Println(\"Original Function\")")
 }
 
 // Test not replacing an existing function when overwrite is false
@@ -492,14 +398,10 @@ func ExistingFunction() {
 	fmt.Println("Replaced Function")
 }
 `
-	file := parseCode(t, src)
-	newDecl := parseCode(t, newDeclSrc).Decls[0]
-
-	upsertDeclaration(file, newDecl, false)
-
-	result := formatCode(t, file)
-	require.Contains(t, result, "fmt.Println(\"Original Function\")")
-	require.NotContains(t, result, "fmt.Println(\"Replaced Function\")")
+	result, err := upsertCodeFragments("main.go", []byte(src), []CodeFragment{{Content: newDeclSrc}})
+	require.NoError(t, err)
+	require.Contains(t, string(result), "fmt.Println(\"Original Function\")")
+	require.NotContains(t, string(result), "fmt.Println(\"Replaced Function\")")
 }
 
 // Test appending a type when there are multiple types
@@ -525,13 +427,10 @@ type NewType struct {
 	Name string
 }
 `
-	file := parseCode(t, src)
-	newDecl := parseCode(t, newDeclSrc).Decls[0]
-
-	upsertDeclaration(file, newDecl, false)
+	result, err := upsertCodeFragments("main.go", []byte(src), []CodeFragment{{Content: newDeclSrc}})
+	require.NoError(t, err)
 
-	result := formatCode(t, file)
-	structs := getMapStructsFieldsType(result)
+	structs := getMapStructsFieldsType(string(result))
 	require.Len(t, structs, 3)
 	require.Contains(t, structs, "NewType")
 	require.Len(t, structs["NewType"], 2)
@@ -562,15 +461,252 @@ type NewType struct {
 	Id int
 }
 `
-	file := parseCode(t, src)
-	newDecl := parseCode(t, newDeclSrc)
+	result, err := upsertCodeFragments("main.go", []byte(src), []CodeFragment{{Content: newDeclSrc}})
+	require.NoError(t, err)
+	require.NotContains(t, string(result), `import "strings"`)
+	require.Contains(t, string(result), "import (\n\t\"fmt\"\n\t\"strings\"\n)", "merged into the imports of the file")
+}
+
+func TestUpsertCodeFragments_Errors(t *testing.T) {
+	src := "package main\n\nfunc Hello() {}\n"
+	_, err := upsertCodeFragments("main.go", []byte(src), []CodeFragment{
+		{Content: "func Bye() {}"},
+		{Content: "func {"},
+	})
+	require.ErrorContains(t, err, "fragment 1: failed to parse fragment")
+
+	_, err = upsertCodeFragments("main.go", []byte(src), []CodeFragment{{Operation: "rename"}})
+	require.ErrorContains(t, err, `fragment 0: unknown fragment operation "rename"`)
+
+	// No file is written when a fragment fails
+	dir := t.TempDir()
+	good, bad := filepath.Join(dir, "good.go"), filepath.Join(dir, "bad.go")
+	require.NoError(t, os.WriteFile(good, []byte(src), 0644))
+	require.NoError(t, os.WriteFile(bad, []byte(src), 0644))
+	err = InsertCodeFragments(map[string][]CodeFragment{
+		good: {{Content: "func Bye() {}"}},
+		bad:  {{Content: "func Bye() {}"}, {Content: "func {"}},
+	})
+	var changeSetErr *ChangeSetError
+	require.ErrorAs(t, err, &changeSetErr)
+	require.Len(t, changeSetErr.Failures, 1)
+	require.Equal(t, bad, changeSetErr.Failures[0].File)
+	require.Equal(t, 1, changeSetErr.Failures[0].Fragment)
+	for _, file := range []string{good, bad} {
+		content, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Equal(t, src, string(content))
+	}
+
+	// Files are only created once every fragment applies
+	created := filepath.Join(dir, "new", "new.go")
+	err = InsertCodeFragments(map[string][]CodeFragment{created: {{Content: "func {"}}})
+	require.ErrorAs(t, err, &changeSetErr)
+	require.NoDirExists(t, filepath.Join(dir, "new"))
+	require.NoError(t, InsertCodeFragments(map[string][]CodeFragment{created: {{Content: "func New() {}"}}}))
+	content, err := os.ReadFile(created)
+	require.NoError(t, err)
+	require.Equal(t, "package main\n\nfunc New() {}\n", string(content))
+}
+
+func TestUpsertCodeFragments_PreservesComments(t *testing.T) {
+	src := `package main
 
-	for _, decl := range newDecl.Decls {
-		upsertDeclaration(file, decl, false)
-	}
+import "fmt"
+
+// Config holds the settings.
+type Config struct {
+	Name string // name of the app
+}
+
+// floating comment between declarations
+
+// Hello says hello.
+//
+//go:noinline
+func Hello() {
+	// inside the body
+	fmt.Println("hello")
+} // end of Hello
+
+// Bye says bye.
+func Bye() {}
+`
+	fragments := []CodeFragment{
+		{
+			Content: `// Hello greets name.
+func Hello(name string) {
+	// greet
+	fmt.Println("hello", name) // with the name
+}`,
+			Overwrite: true,
+		},
+		{
+			Content: `import "strings"
+
+// Upper returns s in upper case.
+func Upper(s string) string { return strings.ToUpper(s) } // one-liner
+
+// Config is kept since it isn't overwritten.
+type Config struct{}`,
+		},
+	}
+	result, err := upsertCodeFragments("main.go", []byte(src), fragments)
+	require.NoError(t, err)
+	require.Equal(t, `package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Config holds the settings.
+type Config struct {
+	Name string // name of the app
+}
+
+// floating comment between declarations
+
+// Hello greets name.
+func Hello(name string) {
+	// greet
+	fmt.Println("hello", name) // with the name
+}
+
+// Bye says bye.
+func Bye() {}
+
+// Upper returns s in upper case.
+func Upper(s string) string { return strings.ToUpper(s) } // one-liner
+`, string(result))
+
+	// Replacing a declaration with itself leaves the file as is
+	result, err = upsertCodeFragments("main.go", []byte(src), []CodeFragment{{Content: "// Bye says bye.\nfunc Bye() {}", Overwrite: true}})
+	require.NoError(t, err)
+	require.Equal(t, src, string(result))
 
-	result := formatCode(t, file)
-	require.NotContains(t, result, `import "strings"`)
+	_, err = upsertCodeFragments("main.go", []byte("package main\n\nfunc {"), []CodeFragment{{Content: "func A() {}"}})
+	require.Error(t, err)
+}
+
+func TestUpsertCodeFragments_Specs(t *testing.T) {
+	src := `package main
+
+// Limit is the maximum.
+const Limit = 10
+
+// Colors.
+const (
+	// Red is the first color.
+	Red = iota
+	Green // second
+)
+
+var (
+	name, alias = "a", "b"
+	count       int
+)
+
+type (
+	// ID identifies.
+	ID   string
+	Name string
+)
+`
+	fragments := []CodeFragment{
+		{
+			// Green is kept, Blue follows it in its block, Limit is replaced as a single declaration
+			Content: `const (
+	Green // not replaced
+	Blue
+)
+
+// Limit is the new maximum.
+const Limit = 20 // raised`,
+			Overwrite: false,
+		},
+		{Content: "// Limit is the new maximum.\nconst Limit = 20 // raised", Overwrite: true},
+		{
+			Content: `type (
+	// ID identifies an entity.
+	ID int
+	// Age in years.
+	Age int
+)
+
+var count int64
+
+type Extra struct{}`,
+			Overwrite: true,
+		},
+		{
+			// New blocks are kept as blocks
+			Content: `// Sizes.
+const (
+	Small = "s"
+	Large = "l"
+)`,
+		},
+	}
+	result, err := upsertCodeFragments("main.go", []byte(src), fragments)
+	require.NoError(t, err)
+	require.Equal(t, `package main
+
+// Limit is the new maximum.
+const Limit = 20 // raised
+
+// Colors.
+const (
+	// Red is the first color.
+	Red = iota
+	Green // second
+	Blue
+)
+
+var (
+	name, alias = "a", "b"
+	count int64
+)
+
+type (
+	// ID identifies an entity.
+	ID int
+	// Age in years.
+	Age int
+	Name string
+)
+
+type Extra struct{}
+
+// Sizes.
+const (
+	Small = "s"
+	Large = "l"
+)
+`, string(result))
+
+	// A spec declaring other names can't be replaced
+	_, err = stageCodeFragment("main.go", []byte(src), CodeFragment{Content: "var name = \"c\"", Overwrite: true})
+	require.ErrorContains(t, err, "cannot replace name: it's declared together with alias")
+	// Without overwrite, the existing spec is kept as is
+	_, err = stageCodeFragment("main.go", []byte(src), CodeFragment{Content: "var name = \"c\""})
+	require.NoError(t, err)
+}
+
+// upsertCodeFragments returns src with the operations of fragments applied, like the files of a
+// change set. It fails at the first fragment that fails, or when the result doesn't format.
+func upsertCodeFragments(filename string, src []byte, fragments []CodeFragment) ([]byte, error) {
+	for i, fragment := range fragments {
+		updated, err := applyCodeFragment(filename, src, fragment)
+		if err != nil {
+			return nil, fmt.Errorf("fragment %d: %w", i, err)
+		}
+		src = updated
+	}
+	if _, err := format.Source(src); err != nil {
+		return nil, err
+	}
+	return src, nil
 }
 
 // Helper function to parse code into an AST file
@@ -599,13 +735,3 @@ func getMapStructsFieldsType(content string) map[string]map[string]string {
 	}
 	return structs
 }
-
-func formatCode(t *testing.T, file *ast.File) string {
-	var buf bytes.Buffer
-
-	err := printer.Fprint(&buf, token.NewFileSet(), file)
-	if err != nil {
-		t.Fatalf("Failed to format code: %v", err)
-	}
-	return buf.String()
-}
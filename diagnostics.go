@@ -0,0 +1,206 @@
+package codesurgeon
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Severity of a Diagnostic.
+const (
+	SeverityError   = "error"   // The source is invalid, e.g. a syntax error
+	SeverityWarning = "warning" // Something was left out of the parsed information, e.g. an incomplete declaration
+)
+
+// Diagnostic is a problem found in a file parsed in tolerant mode (ParseOptions.Tolerant).
+type Diagnostic struct {
+	File     string   `json:"file"`
+	Position Position `json:"position"`
+	Message  string   `json:"message"`
+	Severity string   `json:"severity"` // SeverityError or SeverityWarning
+}
+
+// String returns the diagnostic formatted as file:line:column: severity: message.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Position, d.Severity, d.Message)
+}
+
+// parseMode is the mode every Go file is parsed with.
+const parseMode = parser.ParseComments | parser.AllErrors | parser.DeclarationErrors
+
+// parseGoFile parses a Go file. In tolerant mode, syntax errors are returned as diagnostics along with
+// the partial AST built by the parser, from which the incomplete declarations are removed.
+// The name of the returned file is empty when not even its package clause could be parsed.
+func parseGoFile(fset *token.FileSet, fileName string, src any, tolerant bool) (*ast.File, []Diagnostic, error) {
+	file, err := parser.ParseFile(fset, fileName, src, parseMode)
+	if err == nil {
+		return file, nil, nil
+	}
+	var list scanner.ErrorList
+	if !tolerant || !errors.As(err, &list) {
+		return nil, nil, err
+	}
+	diagnostics := make([]Diagnostic, 0, len(list))
+	for _, e := range list {
+		diagnostics = append(diagnostics, newDiagnostic(e.Pos, e.Msg, SeverityError))
+	}
+	diagnostics = append(diagnostics, pruneIncompleteDecls(fset, file)...)
+	return file, diagnostics, nil
+}
+
+// parseGoDir parses the files of dir kept by filter like parser.ParseDir, reading them from src.
+// In tolerant mode, files with syntax errors are kept as parseGoFile does.
+func parseGoDir(fset *token.FileSet, src sourceFS, dir string, filter func(fs.FileInfo) bool, tolerant bool) (map[string]*ast.Package, []Diagnostic, error) {
+	fileNames, err := src.goFiles(dir, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+	packages := make(map[string]*ast.Package)
+	var diagnostics []Diagnostic
+	for _, fileName := range fileNames {
+		content, err := src.readFile(fileName)
+		if err != nil {
+			return nil, nil, err
+		}
+		file, fileDiagnostics, err := parseGoFile(fset, fileName, content, tolerant)
+		if err != nil {
+			return nil, nil, err
+		}
+		diagnostics = append(diagnostics, fileDiagnostics...)
+		if file.Name.Name == "" {
+			continue
+		}
+		pkg, ok := packages[file.Name.Name]
+		if !ok {
+			pkg = &ast.Package{Name: file.Name.Name, Files: make(map[string]*ast.File)}
+			packages[file.Name.Name] = pkg
+		}
+		pkg.Files[fileName] = file
+	}
+	return packages, diagnostics, nil
+}
+
+// pruneIncompleteDecls removes from file the declarations the parser couldn't complete, i.e. whose
+// signature or type holds a bad node, and returns a warning for each of them. Bodies of functions
+// aren't checked since only their calls are extracted. Const blocks are removed as a whole since
+// dropping one of their specs would change the value of iota for the next ones.
+func pruneIncompleteDecls(fset *token.FileSet, file *ast.File) []Diagnostic {
+	var diagnostics []Diagnostic
+	skip := func(node ast.Node, what string) {
+		diagnostics = append(diagnostics, newDiagnostic(fset.Position(node.Pos()), what+" skipped: incomplete declaration", SeverityWarning))
+	}
+
+	decls := file.Decls[:0]
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.BadDecl:
+			continue
+		case *ast.FuncDecl:
+			if (d.Recv != nil && hasBadNode(d.Recv)) || hasBadNode(d.Type) {
+				skip(d, "func "+d.Name.Name)
+				continue
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.CONST {
+				if hasBadNode(d) {
+					skip(d, "const block")
+					continue
+				}
+				break
+			}
+			specs := d.Specs[:0]
+			for _, spec := range d.Specs {
+				if hasBadNode(spec) {
+					skip(spec, d.Tok.String()+" "+specName(spec))
+					continue
+				}
+				specs = append(specs, spec)
+			}
+			if len(specs) == 0 && len(d.Specs) > 0 {
+				continue
+			}
+			d.Specs = specs
+		}
+		decls = append(decls, decl)
+	}
+	file.Decls = decls
+	return diagnostics
+}
+
+// hasBadNode reports whether node holds a node standing for source the parser couldn't parse.
+func hasBadNode(node ast.Node) bool {
+	bad := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.BadExpr, *ast.BadStmt, *ast.BadDecl:
+			bad = true
+		}
+		return !bad
+	})
+	return bad
+}
+
+// specName returns the names declared by spec.
+func specName(spec ast.Spec) string {
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		return s.Name.Name
+	case *ast.ValueSpec:
+		names := make([]string, len(s.Names))
+		for i, name := range s.Names {
+			names[i] = name.Name
+		}
+		return strings.Join(names, ", ")
+	case *ast.ImportSpec:
+		return s.Path.Value
+	}
+	return ""
+}
+
+// newDiagnostic returns a Diagnostic at pos.
+func newDiagnostic(pos token.Position, message string, severity string) Diagnostic {
+	return Diagnostic{
+		File: pos.Filename,
+		Position: Position{
+			File:      pos.Filename,
+			Line:      pos.Line,
+			Column:    pos.Column,
+			Offset:    pos.Offset,
+			EndLine:   pos.Line,
+			EndColumn: pos.Column,
+			EndOffset: pos.Offset,
+		},
+		Message:  message,
+		Severity: severity,
+	}
+}
+
+// packageErrorDiagnostic converts an error reported by go/packages, positioned as file:line:column, to a Diagnostic.
+func packageErrorDiagnostic(e packages.Error) Diagnostic {
+	pos := token.Position{Filename: e.Pos}
+	// Pos is file:line:column or file:line, and the file name may hold colons itself
+	for i := 0; i < 2; i++ {
+		idx := strings.LastIndex(pos.Filename, ":")
+		if idx < 0 {
+			break
+		}
+		n, err := strconv.Atoi(pos.Filename[idx+1:])
+		if err != nil {
+			break
+		}
+		pos.Line, pos.Column = n, pos.Line
+		pos.Filename = pos.Filename[:idx]
+	}
+	if pos.Filename == "-" {
+		pos.Filename = ""
+	}
+	return newDiagnostic(pos, e.Msg, SeverityError)
+}
@@ -0,0 +1,113 @@
+package codesurgeon
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// Directive is a comment addressed to a tool rather than to readers, e.g. //go:generate or //nolint.
+// The Directives of structs, interfaces, types, functions, methods, fields, variables and constants
+// are read from their doc comment, and from their line comment for fields. Next to them, Deprecated
+// is set when the doc comment has a "Deprecated: " paragraph, whose text is in DeprecatedMessage.
+type Directive struct {
+	Name     string   `json:"name"`           // e.g. "go:generate", "go:embed", "go:build", "+build", "nolint" or "export"
+	Args     string   `json:"args,omitempty"` // Text after the name, e.g. "stringer -type=Status" or the linters of //nolint:errcheck
+	Position Position `json:"position"`
+}
+
+// parseDirective parses a // comment written as a directive: //tool:name, like //go:generate or
+// //lint:ignore, the //nolint, //export, //extern and //line comments, and the legacy // +build lines.
+func parseDirective(text string) (Directive, bool) {
+	body, ok := strings.CutPrefix(text, "//")
+	if !ok {
+		return Directive{}, false
+	}
+	if strings.HasPrefix(body, " +build ") {
+		return Directive{Name: "+build", Args: strings.TrimSpace(body[len(" +build "):])}, true
+	}
+	name, args, _ := strings.Cut(body, " ")
+	args = strings.TrimSpace(args)
+	switch {
+	case name == "nolint":
+		return Directive{Name: name, Args: args}, true
+	case strings.HasPrefix(name, "nolint:"):
+		return Directive{Name: "nolint", Args: strings.TrimSpace(name[len("nolint:"):] + " " + args)}, true
+	case name == "export" || name == "extern" || name == "line":
+		return Directive{Name: name, Args: args}, true
+	case isToolDirective(name):
+		return Directive{Name: name, Args: args}, true
+	}
+	return Directive{}, false
+}
+
+// isToolDirective reports whether name is written tool:name, matching //[a-z0-9]+:[a-z0-9].
+func isToolDirective(name string) bool {
+	colon := strings.Index(name, ":")
+	if colon <= 0 || colon+1 >= len(name) {
+		return false
+	}
+	isLowerAlnum := func(c byte) bool {
+		return 'a' <= c && c <= 'z' || '0' <= c && c <= '9'
+	}
+	for i := 0; i < colon; i++ {
+		if !isLowerAlnum(name[i]) {
+			return false
+		}
+	}
+	return isLowerAlnum(name[colon+1])
+}
+
+// extractDirectives returns the directives of the comment groups, in order.
+func extractDirectives(fset *token.FileSet, groups ...*ast.CommentGroup) []Directive {
+	var directives []Directive
+	for _, group := range groups {
+		if group == nil {
+			continue
+		}
+		for _, c := range group.List {
+			if d, ok := parseDirective(c.Text); ok {
+				d.Position = newPosition(fset, c)
+				directives = append(directives, d)
+			}
+		}
+	}
+	return directives
+}
+
+// docComment returns cg without its directives, whose Text is then only meant for readers.
+func docComment(cg *ast.CommentGroup) *ast.CommentGroup {
+	if cg == nil {
+		return nil
+	}
+	doc := &ast.CommentGroup{}
+	for _, c := range cg.List {
+		if _, ok := parseDirective(c.Text); !ok {
+			doc.List = append(doc.List, c)
+		}
+	}
+	return doc
+}
+
+// specDoc returns the doc comment of a spec: its own or, like go/doc does, the one of its
+// declaration when the declaration isn't parenthesized, e.g. //go:embed above var content string.
+func specDoc(doc *ast.CommentGroup, genDecl *ast.GenDecl) *ast.CommentGroup {
+	if doc != nil || genDecl == nil || genDecl.Lparen.IsValid() {
+		return doc
+	}
+	return genDecl.Doc
+}
+
+// deprecation returns the message of the "Deprecated: " paragraph of a doc comment, following the Go convention.
+func deprecation(doc string) (bool, string) {
+	for _, paragraph := range strings.Split(doc, "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if message, ok := strings.CutPrefix(paragraph, "Deprecated: "); ok {
+			return true, strings.Join(strings.Fields(message), " ")
+		}
+		if paragraph == "Deprecated:" {
+			return true, ""
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	codesurgeon "github.com/wricardo/code-surgeon"
+)
+
+func main() {
+	tmp, err := codesurgeon.ParseFile("other.go")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	pkg := tmp.Packages[0]
+
+	var decls []any
+	for _, s := range pkg.Structs {
+		// Rename the json key of every field and add an Email field
+		for i := range s.Fields {
+			s.Fields[i].SetTag("json", s.Fields[i].Name+",omitempty")
+		}
+		email := codesurgeon.Field{Name: "Email", Type: "string"}
+		email.SetTag("json", "email,omitempty")
+		s.Fields = append(s.Fields, email)
+		decls = append(decls, s)
+	}
+	for _, f := range pkg.Functions {
+		// Add a param to every function
+		f.Params = append(f.Params, codesurgeon.Param{Name: "greeting", Type: "string"})
+		decls = append(decls, f)
+	}
+
+	fragment, err := codesurgeon.RenderFragment(true, decls...)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(fragment.Content)
+
+	codesurgeon.InsertCodeFragments(map[string][]codesurgeon.CodeFragment{
+		"other.go": {fragment},
+	})
+}
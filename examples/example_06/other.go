@@ -0,0 +1,12 @@
+package main
+
+// Person is someone the application knows about.
+type Person struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Greet returns a greeting for p.
+func Greet(p Person) string {
+	return "Hello " + p.Name
+}
@@ -12,98 +12,191 @@ import (
 
 // extractParsedInfo extracts parsed information from the AST.
 // moduleName is the name of the module being parsed, as seen on the go.mod file.
-func extractParsedInfo(packages map[string]*ast.Package, moduleName string, relModPath string) (*ParsedInfo, error) {
+// typed holds the type information of the package when it was type-checked, nil otherwise.
+func extractParsedInfo(fset *token.FileSet, packages map[string]*ast.Package, moduleName string, relModPath string, typed *typedPackage) (*ParsedInfo, error) {
 	output := &ParsedInfo{
 		// Packages: make([]Package, 0, len(packages)),
 		Modules: make([]Module, 0, len(packages)),
 	}
 
-	m := &Module{
+	m := newModule(moduleName, relModPath, len(packages))
+	for _, pkg := range packages {
+		outPkg, err := extractPackage(fset, pkg, m.packagePath(pkg.Name), typed)
+		if err != nil {
+			return nil, err
+		}
+		m.Packages = append(m.Packages, outPkg)
+	}
+	output.Modules = append(output.Modules, *m)
+	output.Packages = m.Packages
+
+	ComputeMethodSets(output)
+
+	return output, nil
+}
+
+// newModule returns the Module of the packages found in the relModPath directory of the moduleName module.
+func newModule(moduleName string, relModPath string, packages int) *Module {
+	return &Module{
 		RootModuleName:    moduleName,
 		RelativeDirectory: relModPath,
 		FullName:          moduleName + "/" + strings.TrimLeft(relModPath, "./"),
-		Packages:          make([]Package, 0, len(packages)),
+		Packages:          make([]Package, 0, packages),
 	}
+}
 
-	for _, pkg := range packages {
-		outPkg := Package{
-			Structs:   make([]Struct, 0),
-			Functions: make([]Function, 0),
-			Variables: make([]Variable, 0),
-			Constants: make([]Constant, 0),
-			Imports:   make([]Import, 0),
-		}
+// packagePath returns the import path of the pkgName package of m, used to record the calls to
+// the package itself. Packages parsed outside of a module are identified by their name.
+func (m *Module) packagePath(pkgName string) string {
+	if m.RootModuleName == "" {
+		return pkgName
+	}
+	return m.importPath()
+}
 
-		docPkg := doc.New(pkg, "", doc.AllDecls|doc.AllMethods|doc.PreserveAST)
-		outPkg.Package = pkg.Name // Set package name
+// importPath returns the import path of the packages of m. FullName keeps a trailing slash
+// for the root directory of a module, which isn't part of the import path.
+func (m *Module) importPath() string {
+	return strings.TrimSuffix(m.FullName, "/")
+}
 
-		// Extract imports
-		imports, err := extractImports(pkg)
-		if err != nil {
-			return nil, err
-		}
-		outPkg.Imports = imports
+// extractPackage extracts a package from its AST.
+func extractPackage(fset *token.FileSet, pkg *ast.Package, pkgPath string, typed *typedPackage) (Package, error) {
+	outPkg := Package{
+		Structs:   make([]Struct, 0),
+		Functions: make([]Function, 0),
+		Variables: make([]Variable, 0),
+		Constants: make([]Constant, 0),
+		Imports:   make([]Import, 0),
+		Types:     make([]TypeDecl, 0),
+		Enums:     make([]Enum, 0),
+
+		typed: typed,
+	}
 
-		// Extract types (structs and interfaces)
-		structs, err := extractStructs(docPkg, outPkg)
-		if err != nil {
-			return nil, err
-		}
-		outPkg.Structs = append(outPkg.Structs, structs...)
+	docPkg := doc.New(pkg, "", doc.AllDecls|doc.AllMethods|doc.PreserveAST)
+	outPkg.Package = pkg.Name // Set package name
+	outPkg.ImportPath = importPath(pkgPath, pkg.Name)
+	outPkg.Docs = getDocsForStruct(docPkg.Doc)
 
-		interfaces, err := extractInterfaces(docPkg, outPkg)
-		if err != nil {
-			return nil, err
-		}
-		outPkg.Interfaces = append(outPkg.Interfaces, interfaces...)
+	outPkg.Files = extractFiles(fset, pkg)
+	outPkg.IsTest = isTestPackage(outPkg.Files)
 
-		// Build a map of structs for easy lookup
-		structMap := make(map[string]*Struct)
-		for i := range outPkg.Structs {
-			structMap[outPkg.Structs[i].Name] = &outPkg.Structs[i]
-		}
+	// Extract imports
+	imports, err := extractImports(pkg)
+	if err != nil {
+		return outPkg, err
+	}
+	outPkg.Imports = imports
 
-		// Extract functions and methods
-		functions, methods, err := extractFunctionsAndMethods(pkg, outPkg)
-		if err != nil {
-			return nil, err
-		}
-		outPkg.Functions = append(outPkg.Functions, functions...)
-
-		// Associate methods with structs
-		for _, method := range methods {
-			receiverName := strings.TrimPrefix(method.Receiver, "*")
-			if structPtr, ok := structMap[receiverName]; ok {
-				// Append to struct's methods
-				method.PtrStruct = structPtr
-				structPtr.Methods = append(structPtr.Methods, method)
-			} else {
-				// Optionally, handle methods for types not defined in this package
-				// For now, we ignore them
+	// Extract types (structs and interfaces)
+	structs, err := extractStructs(fset, docPkg, outPkg)
+	if err != nil {
+		return outPkg, err
+	}
+	outPkg.Structs = append(outPkg.Structs, structs...)
+
+	interfaces, err := extractInterfaces(fset, docPkg, outPkg)
+	if err != nil {
+		return outPkg, err
+	}
+	outPkg.Interfaces = append(outPkg.Interfaces, interfaces...)
+
+	typeDecls, err := extractTypeDecls(fset, docPkg, outPkg)
+	if err != nil {
+		return outPkg, err
+	}
+	outPkg.Types = append(outPkg.Types, typeDecls...)
+
+	// Extract functions and methods
+	functions, methods, err := extractFunctionsAndMethods(fset, pkg, outPkg, pkgPath)
+	if err != nil {
+		return outPkg, err
+	}
+	outPkg.Functions = append(outPkg.Functions, functions...)
+
+	// Associate methods with structs
+	attachMethods(&outPkg, methods)
+
+	// Extract constants and variables
+	constants, variables, err := extractConstantsVariables(fset, pkg, outPkg)
+	if err != nil {
+		return outPkg, err
+	}
+	outPkg.Constants = append(outPkg.Constants, constants...)
+	outPkg.Variables = append(outPkg.Variables, variables...)
+
+	enums, err := extractEnums(fset, pkg)
+	if err != nil {
+		return outPkg, err
+	}
+	outPkg.Enums = append(outPkg.Enums, enums...)
+
+	return outPkg, nil
+}
+
+// importPath returns the import path of the pkgName package found at pkgPath. External test
+// packages, e.g. foo_test, are imported with the _test suffix like go list reports them.
+func importPath(pkgPath string, pkgName string) string {
+	if strings.HasSuffix(pkgName, "_test") && pkgPath != pkgName {
+		return pkgPath + "_test"
+	}
+	return pkgPath
+}
+
+// attachMethods appends methods to the struct or named type of pkg they're declared on.
+// Methods of types declared in no file of pkg are dropped.
+func attachMethods(pkg *Package, methods []Method) {
+	// Build a map of structs and named types for easy lookup
+	structMap := make(map[string]*Struct)
+	for i := range pkg.Structs {
+		structMap[pkg.Structs[i].Name] = &pkg.Structs[i]
+	}
+	typeDeclMap := make(map[string]*TypeDecl)
+	for i := range pkg.Types {
+		typeDeclMap[pkg.Types[i].Name] = &pkg.Types[i]
+	}
+
+	for _, method := range methods {
+		receiverName := method.ReceiverTypeName()
+		if structPtr, ok := structMap[receiverName]; ok {
+			// Generic receivers only name the type parameters, take the constraints from the struct
+			for i := range method.TypeParams {
+				if i < len(structPtr.TypeParams) {
+					method.TypeParams[i].Constraint = structPtr.TypeParams[i].Constraint
+				}
+			}
+			// Append to struct's methods
+			method.PtrStruct = structPtr
+			structPtr.Methods = append(structPtr.Methods, method)
+		} else if typeDeclPtr, ok := typeDeclMap[receiverName]; ok {
+			for i := range method.TypeParams {
+				if i < len(typeDeclPtr.TypeParams) {
+					method.TypeParams[i].Constraint = typeDeclPtr.TypeParams[i].Constraint
+				}
 			}
+			typeDeclPtr.Methods = append(typeDeclPtr.Methods, method)
 		}
+	}
+}
 
-		// Extract constants and variables
-		constants, variables, err := extractConstantsVariables(pkg, outPkg)
-		if err != nil {
-			return nil, err
+// isTestPackage reports whether every file of a package is a _test.go file.
+func isTestPackage(files []SourceFile) bool {
+	for _, f := range files {
+		if !f.IsTest {
+			return false
 		}
-		outPkg.Constants = append(outPkg.Constants, constants...)
-		outPkg.Variables = append(outPkg.Variables, variables...)
-
-		m.Packages = append(m.Packages, outPkg)
 	}
-	output.Modules = append(output.Modules, *m)
-	output.Packages = m.Packages
-
-	return output, nil
+	return len(files) > 0
 }
 
 // parseFunctionDecl extracts function details from an *ast.FuncDecl node.
-func parseFunctionDecl(funcDecl *ast.FuncDecl, docs string, pkg Package) (Function, error) {
+func parseFunctionDecl(fset *token.FileSet, funcDecl *ast.FuncDecl, docs string, pkg Package) (Function, error) {
 	function := Function{
-		Name: funcDecl.Name.Name,
-		Docs: getDocsForField([]string{docs}),
+		Name:       funcDecl.Name.Name,
+		TypeParams: extractTypeParams(funcDecl.Type.TypeParams),
+		Docs:       getDocsForField([]string{docs}),
+		Position:   newPosition(fset, funcDecl),
 	}
 
 	// Parse function parameters
@@ -201,7 +294,8 @@ func parseFunctionDecl(funcDecl *ast.FuncDecl, docs string, pkg Package) (Functi
 	}
 
 	// Adjust the signature formatting
-	signature := fmt.Sprintf("%s(%s)", function.Name, strings.Join(paramStrings, ", "))
+	typeParams := formatTypeParams(funcDecl.Type.TypeParams)
+	signature := fmt.Sprintf("%s%s(%s)", function.Name, typeParams, strings.Join(paramStrings, ", "))
 	if len(returnStrings) > 0 {
 		returnsStr := strings.Join(returnStrings, ", ")
 		if len(function.Returns) > 1 {
@@ -213,7 +307,7 @@ func parseFunctionDecl(funcDecl *ast.FuncDecl, docs string, pkg Package) (Functi
 	function.Signature = signature
 
 	// Generate the full function definition
-	definition := fmt.Sprintf("func %s(%s)", function.Name, strings.Join(paramStrings, ", "))
+	definition := fmt.Sprintf("func %s%s(%s)", function.Name, typeParams, strings.Join(paramStrings, ", "))
 	if len(returnStrings) > 0 {
 		returnsStr := strings.Join(returnStrings, ", ")
 		if len(function.Returns) > 1 {
@@ -227,12 +321,17 @@ func parseFunctionDecl(funcDecl *ast.FuncDecl, docs string, pkg Package) (Functi
 }
 
 // parseMethodDecl extracts method details from an *ast.FuncDecl node.
-func parseMethodDecl(funcDecl *ast.FuncDecl, docs string, ourPkg Package) (Method, error) {
+func parseMethodDecl(fset *token.FileSet, funcDecl *ast.FuncDecl, docs string, ourPkg Package) (Method, error) {
 	receiverType, _ := getFullType(funcDecl.Recv.List[0].Type, ourPkg)
 	method := Method{
-		Name:     funcDecl.Name.Name,
-		Receiver: receiverType.TypeName,
-		Docs:     getDocsForField([]string{docs}),
+		Name:       funcDecl.Name.Name,
+		Receiver:   receiverType.TypeName,
+		TypeParams: receiverTypeParams(funcDecl.Recv.List[0].Type),
+		Docs:       getDocsForField([]string{docs}),
+		Position:   newPosition(fset, funcDecl),
+	}
+	if names := funcDecl.Recv.List[0].Names; len(names) > 0 {
+		method.ReceiverName = names[0].Name
 	}
 
 	// Parse method parameters
@@ -367,26 +466,39 @@ func parseMethodDecl(funcDecl *ast.FuncDecl, docs string, ourPkg Package) (Metho
 }
 
 // extractFunctionsAndMethods traverses the AST to extract all functions and methods.
-func extractFunctionsAndMethods(pkg *ast.Package, ourPkg Package) ([]Function, []Method, error) {
+// pkgPath is the import path of the package, used to resolve the calls made in their bodies.
+func extractFunctionsAndMethods(fset *token.FileSet, pkg *ast.Package, ourPkg Package, pkgPath string) ([]Function, []Method, error) {
 	var functions []Function
 	var methods []Method
 
-	for _, file := range pkg.Files {
+	for _, file := range sortedFiles(pkg) {
+		scope := newCallScope(fset, file, ourPkg, pkgPath)
 		for _, decl := range file.Decls {
 			if funcDecl, ok := decl.(*ast.FuncDecl); ok {
 				if funcDecl.Recv == nil {
 					// Package-level function
-					function, err := parseFunctionDecl(funcDecl, funcDecl.Doc.Text(), ourPkg)
+					function, err := parseFunctionDecl(fset, funcDecl, docComment(funcDecl.Doc).Text(), ourPkg)
 					if err != nil {
 						return nil, nil, err
 					}
+					function.Directives = extractDirectives(fset, funcDecl.Doc)
+					function.Deprecated, function.DeprecatedMessage = deprecation(funcDecl.Doc.Text())
+					function.Calls = extractCalls(funcDecl.Body, scope)
 					functions = append(functions, function)
 				} else {
 					// Method
-					method, err := parseMethodDecl(funcDecl, funcDecl.Doc.Text(), ourPkg)
+					method, err := parseMethodDecl(fset, funcDecl, docComment(funcDecl.Doc).Text(), ourPkg)
 					if err != nil {
 						return nil, nil, err
 					}
+					methodScope := scope
+					if names := funcDecl.Recv.List[0].Names; len(names) > 0 {
+						methodScope.recvName = names[0].Name
+						methodScope.recvType = method.Receiver
+					}
+					method.Directives = extractDirectives(fset, funcDecl.Doc)
+					method.Deprecated, method.DeprecatedMessage = deprecation(funcDecl.Doc.Text())
+					method.Calls = extractCalls(funcDecl.Body, methodScope)
 					methods = append(methods, method)
 				}
 			}
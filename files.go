@@ -0,0 +1,156 @@
+package codesurgeon
+
+import (
+	"go/ast"
+	"go/build"
+	"go/build/constraint"
+	"go/token"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// extractFiles returns the files of pkg sorted by name, with their build constraints, directives, headers and imports.
+func extractFiles(fset *token.FileSet, pkg *ast.Package) []SourceFile {
+	files := make([]SourceFile, 0, len(pkg.Files))
+	for name, file := range pkg.Files {
+		if tf := fset.File(file.Pos()); tf != nil {
+			name = tf.Name()
+		}
+		files = append(files, SourceFile{
+			Name:            name,
+			IsTest:          strings.HasSuffix(name, "_test.go"),
+			BuildConstraint: buildConstraint(file),
+			Directives:      extractDirectives(fset, file.Comments...),
+			Header:          fileHeader(file),
+			Imports:         fileImports(file),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files
+}
+
+// sortedFiles returns the files of pkg sorted by name, so that what is extracted from them
+// is listed in the same order on every parse.
+func sortedFiles(pkg *ast.Package) []*ast.File {
+	names := make([]string, 0, len(pkg.Files))
+	for name := range pkg.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	files := make([]*ast.File, 0, len(names))
+	for _, name := range names {
+		files = append(files, pkg.Files[name])
+	}
+	return files
+}
+
+// buildConstraint returns the build constraint of file: its //go:build expression or, for
+// files that only have the legacy // +build lines, the conjunction of those lines.
+func buildConstraint(file *ast.File) string {
+	var plusBuild constraint.Expr
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		for _, c := range group.List {
+			switch {
+			case constraint.IsGoBuild(c.Text):
+				if expr, err := constraint.Parse(c.Text); err == nil {
+					return expr.String()
+				}
+			case constraint.IsPlusBuild(c.Text):
+				expr, err := constraint.Parse(c.Text)
+				if err != nil {
+					continue
+				}
+				if plusBuild == nil {
+					plusBuild = expr
+				} else {
+					plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+				}
+			}
+		}
+	}
+	if plusBuild == nil {
+		return ""
+	}
+	return plusBuild.String()
+}
+
+// fileHeader returns the comments written before the package clause of file, without the package
+// doc comment and the directives, e.g. a license header. Comment groups are separated by a blank line.
+func fileHeader(file *ast.File) string {
+	var parts []string
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			break
+		}
+		if group == file.Doc {
+			continue
+		}
+		if text := strings.TrimSpace(docComment(group).Text()); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// fileImports returns the imports of file in the order they're written.
+func fileImports(file *ast.File) []Import {
+	var imports []Import
+	for _, spec := range file.Imports {
+		imp := Import{Path: strings.Trim(spec.Path.Value, "\"`")}
+		if spec.Name != nil {
+			imp.Name = spec.Name.Name
+		}
+		imports = append(imports, imp)
+	}
+	return imports
+}
+
+// FileFilter returns a filter for ParseDirectoryWithFilter keeping the files of dir that
+// go build would compile for opts.GOOS, opts.GOARCH and opts.BuildTags, using the current
+// platform when they are empty. _test.go files are kept when opts.Tests is set.
+// With opts.AllFiles, every file is kept.
+func (opts ParseOptions) FileFilter(dir string) func(fs.FileInfo) bool {
+	return opts.fileFilter(dir, diskSource(opts.Overlay))
+}
+
+// fileFilter is FileFilter reading the build constraints of the files from src.
+func (opts ParseOptions) fileFilter(dir string, src sourceFS) func(fs.FileInfo) bool {
+	ctxt := opts.buildContext()
+	if src.fsys != nil || len(src.overlay) > 0 {
+		ctxt.JoinPath = src.join
+		ctxt.OpenFile = src.openFile
+	}
+	return func(fi fs.FileInfo) bool {
+		if opts.AllFiles {
+			return true
+		}
+		if !opts.Tests && strings.HasSuffix(fi.Name(), "_test.go") {
+			return false
+		}
+		match, err := ctxt.MatchFile(dir, fi.Name())
+		return err == nil && match
+	}
+}
+
+// buildContext returns the build.Context go build would use with opts.
+func (opts ParseOptions) buildContext() build.Context {
+	ctxt := build.Default
+	if opts.GOOS != "" {
+		ctxt.GOOS = opts.GOOS
+	}
+	if opts.GOARCH != "" {
+		ctxt.GOARCH = opts.GOARCH
+	}
+	if ctxt.GOOS != build.Default.GOOS || ctxt.GOARCH != build.Default.GOARCH {
+		// Like go build, cross-compiling disables cgo
+		ctxt.CgoEnabled = false
+	}
+	ctxt.BuildTags = opts.BuildTags
+	return ctxt
+}
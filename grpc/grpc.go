@@ -141,10 +141,23 @@ func (h *Handler) ParseCodebase(ctx context.Context, req *connect.Request[api.Pa
 	tags := req.Msg.Tags
 	ignoreRule := req.Msg.IgnoreRule
 
+	opts := codesurgeon.ParseOptions{AllFiles: true}
+	if req.Msg.UseCache {
+		cache, err := codesurgeon.OpenModuleParseCache(path)
+		if err != nil {
+			return nil, err
+		}
+		opts.Cache = cache
+	}
+	if len(req.Msg.Overlay) > 0 {
+		opts.Overlay = make(map[string][]byte, len(req.Msg.Overlay))
+		for name, content := range req.Msg.Overlay {
+			opts.Overlay[name] = []byte(content)
+		}
+	}
+
 	// Call the ParseDirectory function to parse the codebase with all flags
-	parsedInfo, err := codesurgeon.ParseDirectoryRecursive(
-		path,
-	)
+	parsedInfo, err := codesurgeon.ParseDirectoryRecursiveWithOptions(path, opts)
 	if err != nil {
 		log.Printf("Error parsing codebase: %v", err)
 		return connect.NewResponse(&api.ParseCodebaseResponse{
@@ -154,9 +167,49 @@ func (h *Handler) ParseCodebase(ctx context.Context, req *connect.Request[api.Pa
 
 	// Prepare the response
 	response := &api.ParseCodebaseResponse{
-		ParsedInfo: codesurgeon.PrettyPrint(parsedInfo, format, ignoreRule, plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, comments, tags),
+		ParsedInfo: codesurgeon.PrettyPrint(parsedInfo, format, ignoreRule, plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, tags, comments, req.Msg.TagKeys...),
+	}
+
+	return connect.NewResponse(response), nil
+}
+
+func (h *Handler) ApplyFileChanges(ctx context.Context, req *connect.Request[api.ApplyFileChangesRequest]) (*connect.Response[api.ApplyFileChangesResponse], error) {
+	changes := make([]codesurgeon.FileChange, 0, len(req.Msg.Changes))
+	for _, c := range req.Msg.Changes {
+		change := codesurgeon.FileChange{PackageName: c.PackageName, File: c.File}
+		for _, f := range c.Fragments {
+			fragment := codesurgeon.CodeFragment{Content: f.Content, Overwrite: f.Overwrite, Operation: f.Operation}
+			for _, s := range f.Selectors {
+				fragment.Selectors = append(fragment.Selectors, codesurgeon.DeclSelector{Receiver: s.Receiver, Name: s.Name})
+			}
+			change.Fragments = append(change.Fragments, fragment)
+		}
+		changes = append(changes, change)
+	}
+
+	// The previews are returned in both modes, so that the applied changes can be reviewed too
+	apply := codesurgeon.ApplyChangeSet
+	if req.Msg.DryRun {
+		apply = codesurgeon.PreviewFileChanges
+	}
+	previews, err := apply(changes)
+	var changeSetErr *codesurgeon.ChangeSetError
+	if errors.As(err, &changeSetErr) {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	} else if err != nil {
+		return nil, err
 	}
 
+	response := &api.ApplyFileChangesResponse{}
+	for _, p := range previews {
+		response.Files = append(response.Files, &api.FilePreview{
+			File:       p.File,
+			OldContent: p.OldContent,
+			NewContent: p.NewContent,
+			Diff:       p.Diff,
+			IsNew:      p.IsNew,
+		})
+	}
 	return connect.NewResponse(response), nil
 }
 
@@ -0,0 +1,133 @@
+package codesurgeon
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+// Implementation links a struct to an interface satisfied by its method set.
+type Implementation struct {
+	Name    string `json:"name"`    // Interface in Struct.Implements, struct in Interface.Implementers
+	Package string `json:"package"` // Import path of the package declaring it, its name when parsed outside of a module
+	Pointer bool   `json:"pointer"` // Only the pointer to the struct implements the interface
+}
+
+// computeImplementations matches the method set of every struct against every interface of the index.
+// Empty interfaces, constraint interfaces and interfaces embedding types outside of the index are skipped,
+// since their method sets would match every struct or are unknown.
+func (idx methodSetIndex) computeImplementations() {
+	var structs, ifaces []*methodSetType
+	for _, byName := range idx.types {
+		for _, t := range byName {
+			switch {
+			case t.strct != nil:
+				t.strct.Implements = nil
+				structs = append(structs, t)
+			case t.iface != nil:
+				t.iface.Implementers = nil
+				if len(t.iface.MethodSet) > 0 && len(t.iface.Unions) == 0 && len(t.iface.TypeParams) == 0 && t.complete {
+					ifaces = append(ifaces, t)
+				}
+			}
+		}
+	}
+
+	for _, s := range structs {
+		valueSet := make(map[string]bool)
+		pointerSet := make(map[string]bool)
+		for _, entry := range s.strct.MethodSet {
+			pointerSet[entry.key] = true
+			if !entry.Pointer {
+				valueSet[entry.key] = true
+			}
+		}
+		for _, i := range ifaces {
+			value, pointer := true, true
+			for _, entry := range i.iface.MethodSet {
+				value = value && valueSet[entry.key]
+				pointer = pointer && pointerSet[entry.key]
+			}
+			if !pointer {
+				continue
+			}
+			s.strct.Implements = append(s.strct.Implements, Implementation{Name: i.iface.Name, Package: i.pkgPath, Pointer: !value})
+			i.iface.Implementers = append(i.iface.Implementers, Implementation{Name: s.strct.Name, Package: s.pkgPath, Pointer: !value})
+		}
+	}
+
+	for _, s := range structs {
+		sortImplementations(s.strct.Implements)
+	}
+	for _, i := range ifaces {
+		sortImplementations(i.iface.Implementers)
+	}
+}
+
+// methodKey identifies a method by its name and the types of its parameters and results, with the
+// package qualifiers replaced by import paths so that methods of different packages can be compared.
+func methodKey(t *methodSetType, m Method) string {
+	var b strings.Builder
+	b.WriteString(m.Name)
+	b.WriteString("(")
+	for i, p := range m.Params {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(qualifyType(t, p.Type))
+	}
+	b.WriteString(")(")
+	for i, r := range m.Returns {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(qualifyType(t, r.Type))
+	}
+	b.WriteString(")")
+	return b.String()
+}
+
+// qualifyType rewrites a type expression written in the package of t so that every named type
+// is qualified with the import path of its package, e.g. "[]*m.Item" to "[]*example.com/shop/model.Item".
+// Names of parameters and results of function types are dropped.
+func qualifyType(t *methodSetType, typeExpr string) string {
+	variadic := strings.HasPrefix(typeExpr, "...")
+	expr, err := parser.ParseExpr(strings.TrimPrefix(typeExpr, "..."))
+	if err != nil {
+		return typeExpr
+	}
+	ast.Inspect(expr, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.Field:
+			n.Names = nil
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok {
+				if importPath := t.importPath(x.Name); importPath != "" {
+					x.Name = importPath
+				}
+			}
+			return false
+		case *ast.Ident:
+			if types.Universe.Lookup(n.Name) == nil {
+				n.Name = t.pkgPath + "." + n.Name
+			}
+		}
+		return true
+	})
+	qualified := exprToString(expr)
+	if variadic {
+		qualified = "..." + qualified
+	}
+	return qualified
+}
+
+func sortImplementations(impls []Implementation) {
+	sort.Slice(impls, func(i, j int) bool {
+		if impls[i].Package != impls[j].Package {
+			return impls[i].Package < impls[j].Package
+		}
+		return impls[i].Name < impls[j].Name
+	})
+}
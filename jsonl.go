@@ -0,0 +1,141 @@
+package codesurgeon
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Kinds of the records of the jsonl format.
+const (
+	RecordPackage    = "package"
+	RecordStruct     = "struct"
+	RecordInterface  = "interface"
+	RecordType       = "type"
+	RecordFunction   = "function"
+	RecordMethod     = "method"
+	RecordVariable   = "variable"
+	RecordConstant   = "constant"
+	RecordEnum       = "enum"
+	RecordDiagnostic = "diagnostic"
+)
+
+// Record is a line of the jsonl format, describing one entity of the parsed packages.
+type Record struct {
+	Kind     string    `json:"kind"`               // One of the Record constants
+	Package  string    `json:"package"`            // Import path of the package of the entity
+	Name     string    `json:"name"`               // Name of the entity, "Type.Method" for methods
+	Position *Position `json:"position,omitempty"` // Unset for packages
+	Payload  any       `json:"payload"`            // The entity itself, e.g. a Struct. Methods are only in their own records.
+}
+
+// packageRecord is the payload of the package records, the members of the package being in their own records.
+type packageRecord struct {
+	Package    string       `json:"package"`
+	ModuleName string       `json:"module_name"`
+	ImportPath string       `json:"import_path,omitempty"`
+	Docs       []string     `json:"docs,omitempty"`
+	Imports    []Import     `json:"imports,omitempty"`
+	Files      []SourceFile `json:"files,omitempty"`
+	IsTest     bool         `json:"is_test,omitempty"`
+}
+
+// WriteJSONL writes the entities of parsed as JSON Lines, one Record per line, as they're encoded
+// instead of marshalling a single document, so that large repositories can be piped into jq,
+// databases or other indexers. A package record comes before the records of its members.
+func WriteJSONL(w io.Writer, parsed ...*ParsedInfo) error {
+	enc := json.NewEncoder(w)
+	for _, info := range parsed {
+		for _, d := range info.Diagnostics {
+			if err := enc.Encode(Record{Kind: RecordDiagnostic, Name: d.Message, Position: &d.Position, Payload: d}); err != nil {
+				return err
+			}
+		}
+		for _, pkg := range info.Packages {
+			if err := writePackageRecords(enc, pkg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// writePackageRecords writes the records of pkg and of its members.
+func writePackageRecords(enc *json.Encoder, pkg Package) error {
+	pkgPath := pkg.ImportPath
+	if pkgPath == "" {
+		pkgPath = pkg.Package
+	}
+	encode := func(kind, name string, pos Position, payload any) error {
+		record := Record{Kind: kind, Package: pkgPath, Name: name, Payload: payload}
+		if pos.IsValid() {
+			record.Position = &pos
+		}
+		return enc.Encode(record)
+	}
+	methods := func(typeName string, list []Method) error {
+		for _, m := range list {
+			if err := encode(RecordMethod, typeName+"."+m.Name, m.Position, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := encode(RecordPackage, pkg.Package, Position{}, packageRecord{
+		Package:    pkg.Package,
+		ModuleName: pkg.ModuleName,
+		ImportPath: pkg.ImportPath,
+		Docs:       pkg.Docs,
+		Imports:    pkg.Imports,
+		Files:      pkg.Files,
+		IsTest:     pkg.IsTest,
+	}); err != nil {
+		return err
+	}
+	for _, s := range pkg.Structs {
+		payload := s
+		payload.Methods = nil
+		if err := encode(RecordStruct, s.Name, s.Position, payload); err != nil {
+			return err
+		}
+		if err := methods(s.Name, s.Methods); err != nil {
+			return err
+		}
+	}
+	for _, i := range pkg.Interfaces {
+		if err := encode(RecordInterface, i.Name, i.Position, i); err != nil {
+			return err
+		}
+	}
+	for _, t := range pkg.Types {
+		payload := t
+		payload.Methods = nil
+		if err := encode(RecordType, t.Name, t.Position, payload); err != nil {
+			return err
+		}
+		if err := methods(t.Name, t.Methods); err != nil {
+			return err
+		}
+	}
+	for _, f := range pkg.Functions {
+		if err := encode(RecordFunction, f.Name, f.Position, f); err != nil {
+			return err
+		}
+	}
+	for _, v := range pkg.Variables {
+		if err := encode(RecordVariable, v.Name, v.Position, v); err != nil {
+			return err
+		}
+	}
+	for _, c := range pkg.Constants {
+		if err := encode(RecordConstant, c.Name, c.Position, c); err != nil {
+			return err
+		}
+	}
+	for _, e := range pkg.Enums {
+		if err := encode(RecordEnum, e.Type, e.Position, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
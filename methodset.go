@@ -0,0 +1,237 @@
+package codesurgeon
+
+import (
+	"sort"
+	"strings"
+)
+
+// MethodSetEntry is a method that can be called on a value of a struct or interface,
+// either declared on the type itself or promoted from an embedded field.
+type MethodSetEntry struct {
+	Name      string   `json:"name"`
+	Signature string   `json:"signature"`
+	Receiver  string   `json:"receiver"`      // Type declaring the method (e.g. "*Base" or "Reader")
+	Package   string   `json:"package"`       // Package of the type declaring the method
+	Via       []string `json:"via,omitempty"` // Embedded fields traversed to reach the method, empty when declared on the type itself
+	Pointer   bool     `json:"pointer"`       // The method is only in the method set of the pointer type
+
+	key string // Name and parameter and result types qualified with import paths, to compare methods across packages
+}
+
+// methodSetType is a struct, interface or named type found while computing method sets.
+type methodSetType struct {
+	pkg      *Package
+	pkgPath  string
+	strct    *Struct
+	iface    *Interface
+	typeDecl *TypeDecl
+	complete bool // All the embedded interfaces of an interface were resolved
+}
+
+// methodSetIndex finds the types of the parsed module by package path and name.
+type methodSetIndex struct {
+	types map[string]map[string]*methodSetType
+}
+
+// ComputeMethodSets fills the MethodSet of every struct and interface of infos, then
+// the Implements and Implementers lists linking structs to the interfaces they satisfy.
+// Embedded types are resolved across all the packages of infos, so passing every
+// package of a module resolves promotions between them. Embedded types declared
+// outside of infos (e.g. in the standard library) contribute no methods.
+func ComputeMethodSets(infos ...*ParsedInfo) {
+	idx := methodSetIndex{types: make(map[string]map[string]*methodSetType)}
+	for _, info := range infos {
+		if info == nil {
+			continue
+		}
+		for i := range info.Modules {
+			mod := &info.Modules[i]
+			for j := range mod.Packages {
+				idx.add(mod.packagePath(mod.Packages[j].Package), &mod.Packages[j])
+			}
+		}
+	}
+
+	for _, byName := range idx.types {
+		for _, t := range byName {
+			switch {
+			case t.iface != nil:
+				t.iface.MethodSet, t.complete = idx.interfaceMethodSet(t, map[*Interface]bool{})
+			case t.strct != nil:
+				t.strct.MethodSet = idx.structMethodSet(t)
+			}
+		}
+	}
+
+	idx.computeImplementations()
+}
+
+func (idx methodSetIndex) add(pkgPath string, pkg *Package) {
+	byName, ok := idx.types[pkgPath]
+	if !ok {
+		byName = make(map[string]*methodSetType)
+		idx.types[pkgPath] = byName
+	}
+	for i := range pkg.Structs {
+		byName[pkg.Structs[i].Name] = &methodSetType{pkg: pkg, pkgPath: pkgPath, strct: &pkg.Structs[i]}
+	}
+	for i := range pkg.Interfaces {
+		byName[pkg.Interfaces[i].Name] = &methodSetType{pkg: pkg, pkgPath: pkgPath, iface: &pkg.Interfaces[i]}
+	}
+	for i := range pkg.Types {
+		byName[pkg.Types[i].Name] = &methodSetType{pkg: pkg, pkgPath: pkgPath, typeDecl: &pkg.Types[i]}
+	}
+}
+
+// lookup resolves a type expression such as "*Base", "pkg.Base" or "List[T]" used from the package of from.
+func (idx methodSetIndex) lookup(from *methodSetType, typeExpr string) *methodSetType {
+	name := strings.TrimPrefix(typeExpr, "*")
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+
+	pkgPath := from.pkgPath
+	if qualifier, typeName, ok := strings.Cut(name, "."); ok {
+		pkgPath = from.importPath(qualifier)
+		name = typeName
+	}
+	return idx.types[pkgPath][name]
+}
+
+// importPath returns the path of the package imported as qualifier by the package of t.
+func (t *methodSetType) importPath(qualifier string) string {
+	for _, imp := range t.pkg.Imports {
+		if imp.Name == qualifier || (imp.Name == "" && importName(imp.Path) == qualifier) {
+			return imp.Path
+		}
+	}
+	return ""
+}
+
+// interfaceMethodSet returns the methods of an interface, including the ones of its embedded interfaces.
+// complete is false when some embedded interfaces are declared outside of the index.
+func (idx methodSetIndex) interfaceMethodSet(t *methodSetType, visiting map[*Interface]bool) (entries []MethodSetEntry, complete bool) {
+	if visiting[t.iface] {
+		return nil, true
+	}
+	visiting[t.iface] = true
+	defer delete(visiting, t.iface)
+
+	complete = true
+	seen := make(map[string]bool)
+	for _, m := range t.iface.Methods {
+		seen[m.Name] = true
+		entries = append(entries, MethodSetEntry{
+			Name:      m.Name,
+			Signature: m.Signature,
+			Receiver:  t.iface.Name,
+			Package:   t.pkg.Package,
+			key:       methodKey(t, m),
+		})
+	}
+	for _, embed := range t.iface.Embeds {
+		embedded := idx.lookup(t, embed)
+		if embedded == nil || embedded.iface == nil {
+			complete = false
+			continue
+		}
+		embeddedEntries, embeddedComplete := idx.interfaceMethodSet(embedded, visiting)
+		complete = complete && embeddedComplete
+		for _, entry := range embeddedEntries {
+			if seen[entry.Name] {
+				continue
+			}
+			seen[entry.Name] = true
+			entries = append(entries, entry)
+		}
+	}
+	sortMethodSet(entries)
+	return entries, complete
+}
+
+// structMethodSet returns the methods declared on a struct and the ones promoted from its embedded fields.
+// It follows the Go selector rules: shallower methods and fields shadow deeper ones and
+// methods found more than once at the same depth are ambiguous and left out.
+func (idx methodSetIndex) structMethodSet(t *methodSetType) []MethodSetEntry {
+	type candidate struct {
+		t       *methodSetType
+		via     []string
+		pointer bool // Embedded through a pointer, e.g. *Base
+	}
+
+	resolved := make(map[string]bool) // Names found at a shallower depth
+	visited := make(map[*methodSetType]bool)
+	var entries []MethodSetEntry
+	level := []candidate{{t: t}}
+	for len(level) > 0 {
+		found := make(map[string][]MethodSetEntry)
+		names := make(map[string]int)
+		var next []candidate
+		for _, c := range level {
+			if visited[c.t] {
+				continue
+			}
+			visited[c.t] = true
+
+			var methods []Method
+			switch {
+			case c.t.strct != nil:
+				methods = c.t.strct.Methods
+				for _, f := range c.t.strct.Fields {
+					for _, name := range fieldNames(f) {
+						names[name]++
+					}
+					if !f.Embedded {
+						continue
+					}
+					if embedded := idx.lookup(c.t, f.Type); embedded != nil {
+						via := append(append([]string{}, c.via...), f.Name)
+						next = append(next, candidate{t: embedded, via: via, pointer: c.pointer || strings.HasPrefix(f.Type, "*")})
+					}
+				}
+			case c.t.typeDecl != nil:
+				methods = c.t.typeDecl.Methods
+			case c.t.iface != nil:
+				ifaceEntries, _ := idx.interfaceMethodSet(c.t, map[*Interface]bool{})
+				for _, entry := range ifaceEntries {
+					entry.Via = c.via
+					found[entry.Name] = append(found[entry.Name], entry)
+				}
+			}
+			for _, m := range methods {
+				pointerReceiver := strings.HasPrefix(m.Receiver, "*")
+				found[m.Name] = append(found[m.Name], MethodSetEntry{
+					Name:      m.Name,
+					Signature: m.Signature,
+					Receiver:  m.Receiver,
+					Package:   c.t.pkg.Package,
+					Via:       c.via,
+					Pointer:   pointerReceiver && !c.pointer,
+					key:       methodKey(c.t, m),
+				})
+			}
+		}
+
+		for name, candidates := range found {
+			if resolved[name] || len(candidates) > 1 || names[name] > 0 {
+				continue
+			}
+			entries = append(entries, candidates[0])
+		}
+		for name := range found {
+			resolved[name] = true
+		}
+		for name := range names {
+			resolved[name] = true
+		}
+		level = next
+	}
+	sortMethodSet(entries)
+	return entries
+}
+
+func sortMethodSet(entries []MethodSetEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+}
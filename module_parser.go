@@ -0,0 +1,235 @@
+package codesurgeon
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// ParseOptions configures ParseModule and the parsers taking options.
+type ParseOptions struct {
+	Dir       string   // Directory the pattern is resolved from, defaults to the current directory. Only used by ParseModule
+	Tests     bool     // Also load the _test.go files of the packages
+	Env       []string // Environment of the go command, defaults to the current environment. Only used by ParseModule
+	GOOS      string   // Target operating system evaluated in build constraints, defaults to the current one
+	GOARCH    string   // Target architecture evaluated in build constraints, defaults to the current one
+	BuildTags []string // Additional build tags evaluated in build constraints, like go build -tags
+	Workers   int      // Number of packages parsed concurrently by ParseModules, defaults to GOMAXPROCS
+	AllFiles  bool     // Parse every .go file whatever its build constraints, like ParseDirectoryRecursive. Tests, GOOS, GOARCH and BuildTags are ignored
+	Tolerant  bool     // Extract what can be from files with syntax or type errors and report the errors in ParsedInfo.Diagnostics
+
+	Overlay map[string][]byte // Contents replacing files or adding files to directories by path, e.g. unsaved editor buffers. Paths are relative to the fs.FS with ParseFS
+	Cache   *ParseCache       // Reuse the extraction of unchanged files. Only used by ParseModules, ParseDirectoryWithOptions and ParseFS
+}
+
+// typedPackage holds the go/types information of a package loaded by ParseModule.
+type typedPackage struct {
+	info *types.Info
+	pkg  *types.Package
+}
+
+// ParseModule loads the packages matching pattern (e.g. "./...") with go/packages and
+// type-checks them. It returns one ParsedInfo per package, like ParseDirectoryRecursive,
+// but TypeDetails and TypeReference carry fully qualified import paths, the kind of the
+// underlying type and exact builtin detection.
+// It is slower than the syntactic parsers since the dependencies of the packages are
+// type-checked from source, which keeps it independent of the export data format of the
+// installed Go toolchain.
+// With opts.Tolerant, packages with syntax or type errors are extracted from what could be parsed
+// and their errors are reported in the Diagnostics of their ParsedInfo.
+func ParseModule(ctx context.Context, pattern string, opts ParseOptions) ([]*ParsedInfo, error) {
+	fset := token.NewFileSet()
+	cfg := &packages.Config{
+		Context: ctx,
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedSyntax | packages.NeedTypes |
+			packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps | packages.NeedModule,
+		Dir:   opts.Dir,
+		Env:   opts.Env,
+		Tests: opts.Tests,
+		Fset:  fset,
+
+		Overlay: diskSource(opts.Overlay).overlay,
+	}
+	if opts.GOOS != "" || opts.GOARCH != "" {
+		if cfg.Env == nil {
+			cfg.Env = os.Environ()
+		}
+		// Later entries take precedence over the ones of the environment
+		if opts.GOOS != "" {
+			cfg.Env = append(cfg.Env, "GOOS="+opts.GOOS)
+		}
+		if opts.GOARCH != "" {
+			cfg.Env = append(cfg.Env, "GOARCH="+opts.GOARCH)
+		}
+	}
+	if len(opts.BuildTags) > 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(opts.BuildTags, ",")}
+	}
+	pkgs, err := packages.Load(cfg, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("error loading packages: %w", err)
+	}
+
+	// With tests, a package is loaded again with its _test.go files, keep only that variant
+	hasTestVariant := make(map[string]bool)
+	for _, pkg := range pkgs {
+		if pkg.ForTest != "" && pkg.ForTest == pkg.PkgPath {
+			hasTestVariant[pkg.PkgPath] = true
+		}
+	}
+
+	var results []*ParsedInfo
+	for _, pkg := range pkgs {
+		var diagnostics []Diagnostic
+		if len(pkg.Errors) > 0 {
+			if !opts.Tolerant {
+				return nil, fmt.Errorf("error loading package %s: %v", pkg.PkgPath, pkg.Errors[0])
+			}
+			for _, e := range pkg.Errors {
+				diagnostics = append(diagnostics, packageErrorDiagnostic(e))
+			}
+			for _, file := range pkg.Syntax {
+				diagnostics = append(diagnostics, pruneIncompleteDecls(fset, file)...)
+			}
+		}
+		if len(pkg.Syntax) == 0 || (pkg.ForTest == "" && hasTestVariant[pkg.PkgPath]) || strings.HasSuffix(pkg.ID, ".test") {
+			// Nothing to parse, replaced by its test variant or the main package generated by go test
+			continue
+		}
+
+		astPkg := &ast.Package{
+			Name:  pkg.Name,
+			Files: make(map[string]*ast.File, len(pkg.Syntax)),
+		}
+		for _, file := range pkg.Syntax {
+			astPkg.Files[fset.File(file.Pos()).Name()] = file
+		}
+
+		moduleName, relPath, dir := "", "", ""
+		if len(pkg.GoFiles) > 0 {
+			dir = filepath.Dir(pkg.GoFiles[0])
+		}
+		if pkg.Module != nil {
+			moduleName = pkg.Module.Path
+			if rel, err := filepath.Rel(pkg.Module.Dir, dir); err == nil {
+				relPath = rel
+			}
+		}
+
+		parsedInfo, err := extractParsedInfo(fset, map[string]*ast.Package{pkg.Name: astPkg}, moduleName, relPath, &typedPackage{
+			info: pkg.TypesInfo,
+			pkg:  pkg.Types,
+		})
+		if err != nil {
+			return nil, err
+		}
+		parsedInfo.Directory = dir
+		parsedInfo.Diagnostics = diagnostics
+		results = append(results, parsedInfo)
+	}
+
+	ComputeMethodSets(results...)
+	return results, nil
+}
+
+// refineTypeDetails completes the syntactic TypeDetails of an expression with the type
+// computed by the type checker.
+func refineTypeDetails(tr *TypeDetails, t types.Type, current *types.Package) {
+	tr.Kind = typeKind(t)
+
+	// Like the syntactic parser, describe the element type of pointers, slices, maps and channels
+	base := t
+	for {
+		switch b := base.(type) {
+		case *types.Pointer:
+			base = b.Elem()
+			continue
+		case *types.Slice:
+			base = b.Elem()
+			continue
+		case *types.Array:
+			base = b.Elem()
+			continue
+		case *types.Map:
+			base = b.Elem()
+			continue
+		case *types.Chan:
+			base = b.Elem()
+			continue
+		}
+		break
+	}
+
+	switch b := base.(type) {
+	case *types.Basic:
+		tr.IsBuiltin = true
+		tr.IsExternal = false
+		tr.Package = nil
+		tr.PackageName = nil
+		tr.TypeReferences = nil
+	case interface{ Obj() *types.TypeName }: // *types.Named, *types.Alias and *types.TypeParam
+		obj := b.Obj()
+		if obj.Pkg() == nil {
+			// Predeclared types such as error, any or comparable
+			tr.IsBuiltin = true
+			tr.IsExternal = false
+			tr.Package = nil
+			tr.PackageName = nil
+			tr.TypeReferences = nil
+			return
+		}
+		if _, ok := b.(*types.TypeParam); ok {
+			tr.IsBuiltin = false
+			tr.IsExternal = false
+			return
+		}
+		path, name, typeName := obj.Pkg().Path(), obj.Pkg().Name(), obj.Name()
+		tr.Package = &path
+		tr.PackageName = &name
+		tr.Type = &typeName
+		tr.IsBuiltin = false
+		tr.IsExternal = obj.Pkg() != current
+		tr.TypeReferences = []TypeReference{{
+			Package:     &path,
+			PackageName: &name,
+			Name:        typeName,
+		}}
+	}
+}
+
+// typeKind returns the kind of the underlying type of t, e.g. "struct", "map" or "basic".
+func typeKind(t types.Type) string {
+	if _, ok := t.(*types.TypeParam); ok {
+		return "type_param"
+	}
+	switch t.Underlying().(type) {
+	case *types.Basic:
+		return "basic"
+	case *types.Pointer:
+		return "pointer"
+	case *types.Slice:
+		return "slice"
+	case *types.Array:
+		return "array"
+	case *types.Map:
+		return "map"
+	case *types.Chan:
+		return "chan"
+	case *types.Signature:
+		return "func"
+	case *types.Struct:
+		return "struct"
+	case *types.Interface:
+		return "interface"
+	case *types.Tuple:
+		return "tuple"
+	}
+	return ""
+}
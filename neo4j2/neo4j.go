@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"unicode"
 
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/rs/zerolog/log"
@@ -30,11 +31,13 @@ func MergeStruct(ctx context.Context, alias string, mod codesurgeon.Module, pkg
 			"name":    strct.Name,
 			"package": pkg.Package,
 		},
-		SetFields: map[string]string{
-			"documentation": strings.Join(strct.Docs, "\n"),
-			"packageName":   pkg.Package,
-			"definition":    strct.Definition,
-		},
+		SetFields: withPosition(map[string]any{
+			"documentation":     strings.Join(strct.Docs, "\n"),
+			"packageName":       pkg.Package,
+			"definition":        strct.Definition,
+			"deprecated":        strct.Deprecated,
+			"deprecatedMessage": strct.DeprecatedMessage,
+		}, strct.Position),
 	}
 }
 
@@ -84,7 +87,7 @@ func UpsertStruct(ctx context.Context, session neo4j.SessionWithContext, mod cod
 }
 
 // Helper function to merge a Field node
-func MergeField(ctx context.Context, alias string, pkg codesurgeon.Package, fieldName, fieldType, documentation string) MergeQuery {
+func MergeField(ctx context.Context, alias string, pkg codesurgeon.Package, fieldName, fieldType, documentation string, pos codesurgeon.Position) MergeQuery {
 	return MergeQuery{
 		NodeType: "Field",
 		Alias:    alias,
@@ -92,11 +95,11 @@ func MergeField(ctx context.Context, alias string, pkg codesurgeon.Package, fiel
 			"name":    fieldName,
 			"package": pkg.Package,
 		},
-		SetFields: map[string]string{
+		SetFields: withPosition(map[string]any{
 			"documentation": documentation,
 			"packageName":   pkg.Package,
 			"type":          fieldType,
-		},
+		}, pos),
 	}
 }
 
@@ -159,8 +162,10 @@ func MergeBaseType(ctx context.Context, alias string, td codesurgeon.TypeDetails
 // UpsertStructField creates or updates a field node in Neo4j and links it to its struct and package.
 // UpsertStructField creates or updates a field node in Neo4j and links it to its struct and package.
 func UpsertStructField(ctx context.Context, session neo4j.SessionWithContext, mod codesurgeon.Module, pkg codesurgeon.Package, strct codesurgeon.Struct, field codesurgeon.Field) error {
+	fieldQuery := MergeField(ctx, "f", pkg, field.Name, field.Type, strings.Join(field.Docs, "\n"), field.Position)
+	withTags(fieldQuery.SetFields, field.Tags)
 	query := CypherQuery{}.
-		Merge(MergeField(ctx, "f", pkg, field.Name, field.Type, strings.Join(field.Docs, "\n"))).
+		Merge(fieldQuery).
 		Merge(MergeType(ctx, "t", field.TypeDetails)).
 		Merge(MergeBaseType(ctx, "b", field.TypeDetails)).
 		Merge(MergeStruct(ctx, "s", mod, pkg, strct)).
@@ -184,7 +189,7 @@ func UpsertStructField(ctx context.Context, session neo4j.SessionWithContext, mo
 // 			"receiver": receiver,
 // 			"function": methodName,
 // 		},
-// 		SetFields: map[string]string{
+// 		SetFields: map[string]any{
 // 			"name":          methodName,
 // 			"documentation": documentation,
 // 			"packageName":   packageName,
@@ -198,15 +203,17 @@ func MergeMethod(ctx context.Context, alias string, mod codesurgeon.Module, pkg
 		Alias:    alias,
 		Properties: map[string]any{
 			"name":                fn.Name,
-			"receiver":            strings.Replace(fn.Receiver, "*", "", 1),
+			"receiver":            fn.ReceiverTypeName(),
 			"packageName":         pkg.Package,        //short name of the package like "xyz"
 			"packageFullName":     mod.FullName,       //full name of the package like "github.com/wricardo/code-surgeon/examples/xyz"
 			"rootPackageFullName": mod.RootModuleName, //full name of the package like "github.com/wricardo/code-surgeon"
 		},
-		SetFields: map[string]string{
-			"documentation": strings.Join(fn.Docs, "\n"),
-			"definition":    fn.Definition,
-		},
+		SetFields: withPosition(map[string]any{
+			"documentation":     strings.Join(fn.Docs, "\n"),
+			"definition":        fn.Definition,
+			"deprecated":        fn.Deprecated,
+			"deprecatedMessage": fn.DeprecatedMessage,
+		}, fn.Position),
 	}
 }
 
@@ -231,10 +238,12 @@ func MergeFunction(ctx context.Context, alias string, mod codesurgeon.Module, pk
 			"packageFullName":     mod.FullName,
 			"rootPackageFullName": mod.RootModuleName,
 		},
-		SetFields: map[string]string{
-			"documentation": strings.Join(fn.Docs, "\n"),
-			"definition":    fn.Definition,
-		},
+		SetFields: withPosition(map[string]any{
+			"documentation":     strings.Join(fn.Docs, "\n"),
+			"definition":        fn.Definition,
+			"deprecated":        fn.Deprecated,
+			"deprecatedMessage": fn.DeprecatedMessage,
+		}, fn.Position),
 	}
 }
 
@@ -283,13 +292,151 @@ func MergeInterface(ctx context.Context, alias string, mod codesurgeon.Module, p
 			"packageFullName":     mod.FullName,
 			"rootPackageFullName": mod.RootModuleName,
 		},
-		SetFields: map[string]string{
-			"documentation": strings.Join(iface.Docs, "\n"),
-			"definition":    iface.Definition,
+		SetFields: withPosition(map[string]any{
+			"documentation":     strings.Join(iface.Docs, "\n"),
+			"definition":        iface.Definition,
+			"deprecated":        iface.Deprecated,
+			"deprecatedMessage": iface.DeprecatedMessage,
+		}, iface.Position),
+	}
+}
+
+func MergeTypeDecl(ctx context.Context, alias string, mod codesurgeon.Module, pkg codesurgeon.Package, typeDecl codesurgeon.TypeDecl) MergeQuery {
+	return MergeQuery{
+		NodeType: "TypeDecl",
+		Alias:    alias,
+		Properties: map[string]any{
+			"name":                typeDecl.Name,
+			"packageName":         pkg.Package,
+			"packageFullName":     mod.FullName,
+			"rootPackageFullName": mod.RootModuleName,
 		},
+		SetFields: withPosition(map[string]any{
+			"documentation":     strings.Join(typeDecl.Docs, "\n"),
+			"definition":        typeDecl.Definition,
+			"deprecated":        typeDecl.Deprecated,
+			"deprecatedMessage": typeDecl.DeprecatedMessage,
+			"underlying":        typeDecl.Underlying,
+			"isAlias":           typeDecl.IsAlias,
+		}, typeDecl.Position),
 	}
 }
 
+// UpsertTypeDecl creates or updates a named type node in Neo4j and links it to its package and underlying type.
+func UpsertTypeDecl(ctx context.Context, session neo4j.SessionWithContext, mod codesurgeon.Module, pkg codesurgeon.Package, typeDecl codesurgeon.TypeDecl) error {
+	query := CypherQuery{}.
+		Merge(MergeTypeDecl(ctx, "d", mod, pkg, typeDecl)).
+		Merge(MergeType(ctx, "t", typeDecl.TypeDetails)).
+		Merge(MergeBaseType(ctx, "b", typeDecl.TypeDetails)).
+		Merge(MergePackage(ctx, "p", mod, pkg)).
+		MergeRel("d", "BELONGS_TO", "p", nil).
+		MergeRel("d", "UNDERLYING_TYPE", "t", nil).
+		MergeRel("t", "BASE_TYPE", "b", nil).
+		Return("id(d) as nodeID")
+
+	return query.Execute(ctx, session)
+}
+
+// UpsertTypeDeclMethod creates or updates a method node in Neo4j and links it to its receiver TypeDecl.
+func UpsertTypeDeclMethod(ctx context.Context, session neo4j.SessionWithContext, mod codesurgeon.Module, pkg codesurgeon.Package, method codesurgeon.Method, receiver codesurgeon.TypeDecl) error {
+	query := CypherQuery{}.
+		Merge(MergeMethod(ctx, "m", mod, pkg, method)).
+		Merge(MergeTypeDecl(ctx, "d", mod, pkg, receiver)).
+		MergeRel("d", "HAS_METHOD", "m", nil).
+		Return("id(m) as nodeID")
+
+	return query.Execute(ctx, session)
+}
+
+// UpsertFunctionCall links a function to the function or method it calls with a CALLS relationship.
+// Callees missing from the graph, like the ones of unparsed packages or unresolved calls, are skipped.
+func UpsertFunctionCall(ctx context.Context, session neo4j.SessionWithContext, mod codesurgeon.Module, fn codesurgeon.Function, call codesurgeon.CallRef) error {
+	query := CypherQuery{}.
+		Match(MatchQuery{
+			NodeType: "Function",
+			Alias:    "caller",
+			Properties: map[string]any{
+				"name":            fn.Name,
+				"packageFullName": mod.FullName,
+			},
+		}).
+		Where("caller.interfaceName IS NULL")
+
+	return matchCallee(query, call).
+		MergeRel("caller", "CALLS", "callee", nil).
+		Return("count(*) as calls").
+		Execute(ctx, session)
+}
+
+// UpsertMethodCall links a method to the function or method it calls with a CALLS relationship.
+// Callees missing from the graph, like the ones of unparsed packages or unresolved calls, are skipped.
+func UpsertMethodCall(ctx context.Context, session neo4j.SessionWithContext, mod codesurgeon.Module, method codesurgeon.Method, call codesurgeon.CallRef) error {
+	query := CypherQuery{}.
+		Match(MatchQuery{
+			NodeType: "Method",
+			Alias:    "caller",
+			Properties: map[string]any{
+				"name":            method.Name,
+				"receiver":        method.ReceiverTypeName(),
+				"packageFullName": mod.FullName,
+			},
+		})
+
+	return matchCallee(query, call).
+		MergeRel("caller", "CALLS", "callee", nil).
+		Return("count(*) as calls").
+		Execute(ctx, session)
+}
+
+// matchCallee matches the callee of a call as "callee": a Function for function calls, a Method
+// or an interface method for method calls.
+func matchCallee(query CypherQuery, call codesurgeon.CallRef) CypherQuery {
+	query.Args["calleeName"] = call.Name
+	query.Args["calleePackageFullNames"] = packageFullNames(call.Package)
+	if call.Receiver == "" {
+		return query.
+			Raw("MATCH (callee:Function {name: $calleeName})").
+			Where("callee.packageFullName IN $calleePackageFullNames", "callee.interfaceName IS NULL")
+	}
+	query.Args["calleeReceiver"] = call.ReceiverTypeName()
+	return query.
+		Raw("MATCH (callee {name: $calleeName})").
+		Where("callee.packageFullName IN $calleePackageFullNames", "((callee:Method AND callee.receiver = $calleeReceiver) OR (callee:Function AND callee.interfaceName = $calleeReceiver))")
+}
+
+// packageFullNames returns the packageFullName values a package with the importPath import path
+// can be stored with. The root package of a module is keyed by its import path followed by a slash.
+func packageFullNames(importPath string) []string {
+	return []string{importPath, importPath + "/"}
+}
+
+// UpsertImplements links a struct to an interface it implements with an IMPLEMENTS relationship.
+// Interfaces missing from the graph are skipped.
+func UpsertImplements(ctx context.Context, session neo4j.SessionWithContext, pkg codesurgeon.Package, strct codesurgeon.Struct, impl codesurgeon.Implementation) error {
+	query := CypherQuery{}.
+		Match(MatchQuery{
+			NodeType: "Struct",
+			Alias:    "s",
+			Properties: map[string]any{
+				"name":    strct.Name,
+				"package": pkg.Package,
+			},
+		}).
+		Match(MatchQuery{
+			NodeType: "Interface",
+			Alias:    "i",
+			Properties: map[string]any{
+				"name": impl.Name,
+			},
+		}).
+		Where("i.packageFullName IN $interfacePackageFullNames").
+		MergeRel("s", "IMPLEMENTS", "i", map[string]any{"pointer": impl.Pointer}).
+		Return("count(*) as implements")
+	query.Args["interfacePackageFullNames"] = packageFullNames(impl.Package)
+
+	return query.Execute(ctx, session)
+}
+
 func MergeReturn(ctx context.Context, alias string, ret codesurgeon.Param) MergeQuery {
 	return MergeQuery{
 		NodeType: "Return",
@@ -341,7 +488,7 @@ func MergeFuncParam(ctx context.Context, alias string, pkg codesurgeon.Package,
 			"package":    pkg.Package,
 			"methodName": fn.Name,
 		},
-		SetFields: map[string]string{},
+		SetFields: map[string]any{},
 	}
 }
 
@@ -356,7 +503,7 @@ func MergeInterfaceMethodParam(ctx context.Context, alias string, pkg codesurgeo
 			"iface":   iface.Name,
 			"method":  method.Name,
 		},
-		SetFields: map[string]string{
+		SetFields: map[string]any{
 			"name": param.Name,
 			"type": param.Type,
 		},
@@ -373,7 +520,7 @@ func MergeMethodParam(ctx context.Context, alias string, pkg codesurgeon.Package
 			"package":    pkg.Package,
 			"methodName": method.Name,
 		},
-		SetFields: map[string]string{
+		SetFields: map[string]any{
 			"name": param.Name,
 			"type": param.Type,
 		},
@@ -421,10 +568,10 @@ func MergeInterfaceMethodFunction(ctx context.Context, alias string, mod codesur
 			"interfaceName":       iface.Name,
 			"name":                method.Name,
 		},
-		SetFields: map[string]string{
+		SetFields: withPosition(map[string]any{
 			"documentation": strings.Join(method.Docs, "\n"),
 			"definition":    method.Definition,
-		},
+		}, method.Position),
 	}
 }
 
@@ -608,11 +755,59 @@ func BuildMatchQuery(cq MatchQuery) QueryFragment {
 	}
 }
 
+// withPosition adds the source position of an entity to the properties set on its node.
+// Unknown positions leave the properties untouched.
+func withPosition(fields map[string]any, pos codesurgeon.Position) map[string]any {
+	if !pos.IsValid() {
+		return fields
+	}
+	fields["file"] = pos.File
+	fields["line"] = pos.Line
+	fields["column"] = pos.Column
+	fields["offset"] = pos.Offset
+	fields["endLine"] = pos.EndLine
+	fields["endColumn"] = pos.EndColumn
+	fields["endOffset"] = pos.EndOffset
+	return fields
+}
+
+// withTags adds the struct tags of a field to fields: the raw tag, its keys and, for each key, its
+// name and options, e.g. tag_json = "name" and tag_json_options = ["omitempty"].
+func withTags(fields map[string]any, tags []codesurgeon.Tag) map[string]any {
+	if len(tags) == 0 {
+		return fields
+	}
+	keys := make([]string, 0, len(tags))
+	raw := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		keys = append(keys, tag.Key)
+		raw = append(raw, tag.String())
+		property := "tag_" + propertyName(tag.Key)
+		fields[property] = tag.Name
+		if len(tag.Options) > 0 {
+			fields[property+"_options"] = tag.Options
+		}
+	}
+	fields["tag"] = strings.Join(raw, " ")
+	fields["tagKeys"] = keys
+	return fields
+}
+
+// propertyName replaces the characters of name that can't be used in an unquoted property name with _.
+func propertyName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
 type MergeQuery struct {
 	NodeType   string
 	Alias      string
 	Properties map[string]interface{}
-	SetFields  map[string]string
+	SetFields  map[string]any
 }
 
 func BuildMergeQuery(cq MergeQuery) QueryFragment {
@@ -84,7 +84,7 @@ func (s *StackToGraph) reportStackTraceToNeo4j(stackTraceData []ParsedStackEntry
 		Properties: map[string]any{
 			"id": stackHash,
 		},
-		SetFields: map[string]string{},
+		SetFields: map[string]any{},
 	})
 
 	// Execute a write transaction
@@ -526,7 +526,7 @@ func MergeCall(ctx context.Context, alias string, stack_id string, pe ParsedStac
 		NodeType:   "Call",
 		Alias:      alias,
 		Properties: properties,
-		SetFields: map[string]string{
+		SetFields: map[string]any{
 			"id": id,
 		},
 	}
@@ -14,7 +14,20 @@ import (
 	codesurgeon "github.com/wricardo/code-surgeon"
 )
 
+// ToNeo4jOptions configures ToNeo4jWithOptions.
+type ToNeo4jOptions struct {
+	Deep      bool // Look up the file declaring each function and method
+	Recursive bool // Parse every package of the modules instead of their root directory only
+	UseCache  bool // Reuse the parsing of unchanged files from the parse cache of each module
+}
+
+// ToNeo4j parses the modules listed by go list for path and upserts them into Neo4j.
 func ToNeo4j(ctx context.Context, path string, deep bool, myEnv map[string]string, recursive bool) error {
+	return ToNeo4jWithOptions(ctx, path, myEnv, ToNeo4jOptions{Deep: deep, Recursive: recursive})
+}
+
+// ToNeo4jWithOptions parses the modules listed by go list for path like ToNeo4j, configured by opts.
+func ToNeo4jWithOptions(ctx context.Context, path string, myEnv map[string]string, opts ToNeo4jOptions) error {
 	neo4jDbUri, _ := myEnv["NEO4J_DB_URI"]
 	neo4jDbUser, _ := myEnv["NEO4J_DB_USER"]
 	neo4jDbPassword, _ := myEnv["NEO4J_DB_PASSWORD"]
@@ -48,15 +61,24 @@ func ToNeo4j(ctx context.Context, path string, deep bool, myEnv map[string]strin
 			continue
 		}
 
-		if recursive {
+		if opts.Recursive {
 			log.Info().Msgf("Parsed %s", module.Dir)
-			infos, err := codesurgeon.ParseDirectoryRecursive(module.Dir)
+			parseOpts := codesurgeon.ParseOptions{Tests: true, AllFiles: true}
+			if opts.UseCache {
+				if parseOpts.Cache, err = codesurgeon.OpenModuleParseCache(module.Dir); err != nil {
+					return err
+				}
+			}
+			infos, err := codesurgeon.ParseModules(ctx, module.Dir, parseOpts)
 			if err != nil {
 				log.Info().Err(err).Msgf("Error parsing file %s", module.Dir)
 				return err
 			}
 			for _, info := range infos {
-				shouldContinue, err1 := toNeo4j(ctx, info, module.ImportPath, module.Dir, sess, deep)
+				for _, pkgErr := range info.Errors {
+					log.Info().Err(pkgErr).Msg("Error parsing package (skipped)")
+				}
+				shouldContinue, err1 := toNeo4j(ctx, info, module.Dir, module.ImportPath, sess, opts.Deep)
 				if err1 != nil {
 					if shouldContinue {
 						continue
@@ -65,19 +87,34 @@ func ToNeo4j(ctx context.Context, path string, deep bool, myEnv map[string]strin
 					return err1
 				}
 			}
+			// Calls and implementations are linked once every package is in the graph, so the nodes of other packages are found
+			for _, info := range infos {
+				if err := linksToNeo4j(ctx, info, sess); err != nil {
+					return err
+				}
+			}
 		} else {
 			log.Info().Msgf("Parsed %s", module.Dir)
-			info, err := codesurgeon.ParseDirectory(module.Dir)
+			parseOpts := codesurgeon.ParseOptions{AllFiles: true}
+			if opts.UseCache {
+				if parseOpts.Cache, err = codesurgeon.OpenModuleParseCache(module.Dir); err != nil {
+					return err
+				}
+			}
+			info, err := codesurgeon.ParseDirectoryWithOptions(module.Dir, parseOpts)
 			if err != nil {
 				log.Info().Err(err).Msgf("Error parsing file %s", module.Dir)
 			}
 
-			shouldContinue, err1 := toNeo4j(ctx, info, module.Dir, module.ImportPath, sess, deep)
+			shouldContinue, err1 := toNeo4j(ctx, info, module.Dir, module.ImportPath, sess, opts.Deep)
 			if err1 != nil {
 				if shouldContinue {
 					continue
 				}
 			}
+			if err := linksToNeo4j(ctx, info, sess); err != nil {
+				return err
+			}
 
 		}
 
@@ -99,7 +136,7 @@ func toNeo4j(ctx context.Context, info *codesurgeon.ParsedInfo, moduleDir, modul
 
 			for k, struct_ := range pkg.Structs {
 				log.Info().Msgf("struct %d: %s", k, struct_.Name)
-				if err = UpsertStruct(ctx, session, mod, info.Packages[0], struct_); err != nil {
+				if err = UpsertStruct(ctx, session, mod, pkg, struct_); err != nil {
 					log.Info().Err(err).Msgf("Error upserting struct %s", struct_.Name)
 					return true, err
 				}
@@ -148,8 +185,7 @@ func toNeo4j(ctx context.Context, info *codesurgeon.ParsedInfo, moduleDir, modul
 				}
 
 			}
-			fmt.Println("len functions", len(info.Packages[0].Functions))
-			for k, function := range info.Packages[0].Functions {
+			for k, function := range pkg.Functions {
 				funcFilePath := ""
 				if deep {
 					funcFilePath, err = codesurgeon.FindFunction(moduleDir, "", function.Name)
@@ -180,7 +216,35 @@ func toNeo4j(ctx context.Context, info *codesurgeon.ParsedInfo, moduleDir, modul
 					}
 				}
 			}
-			for _, interface_ := range info.Packages[0].Interfaces {
+			for _, typeDecl := range pkg.Types {
+				log.Info().Msgf("type: %s", typeDecl.Name)
+				if err = UpsertTypeDecl(ctx, session, mod, pkg, typeDecl); err != nil {
+					log.Info().Err(err).Msgf("Error upserting type %s", typeDecl.Name)
+					return true, err
+				}
+				for _, method := range typeDecl.Methods {
+					err = UpsertTypeDeclMethod(ctx, session, mod, pkg, method, typeDecl)
+					if err != nil {
+						log.Info().Err(err).Msgf("Error upserting function %s", method.Name)
+						return true, err
+					}
+					for _, param := range method.Params {
+						err = UpsertMethodParam(ctx, session, mod, pkg, codesurgeon.Struct{Name: typeDecl.Name}, method, param)
+						if err != nil {
+							log.Info().Err(err).Msgf("Error upserting function param %s", param.Name)
+							return true, err
+						}
+					}
+					for _, result := range method.Returns {
+						err = UpsertMethodReturn(ctx, session, mod, pkg, method, result)
+						if err != nil {
+							log.Info().Err(err).Msgf("Error upserting function return %s", result.Name)
+							return true, err
+						}
+					}
+				}
+			}
+			for _, interface_ := range pkg.Interfaces {
 				log.Info().Msgf("interface: %s", interface_.Name)
 				if err = UpsertInterface(ctx, session, mod, pkg, interface_); err != nil {
 					log.Info().Err(err).Msgf("Error upserting interface %s", interface_.Name)
@@ -213,6 +277,53 @@ func toNeo4j(ctx context.Context, info *codesurgeon.ParsedInfo, moduleDir, modul
 	return false, nil
 }
 
+// linksToNeo4j writes the CALLS relationships of the functions and methods of info and the
+// IMPLEMENTS relationships of its structs. It expects the nodes they link to be upserted already.
+func linksToNeo4j(ctx context.Context, info *codesurgeon.ParsedInfo, session neo4j.SessionWithContext) error {
+	for _, mod := range info.Modules {
+		for _, pkg := range mod.Packages {
+			for _, struct_ := range pkg.Structs {
+				for _, impl := range struct_.Implements {
+					if err := UpsertImplements(ctx, session, pkg, struct_, impl); err != nil {
+						log.Info().Err(err).Msgf("Error upserting implementation of %s by %s", impl.Name, struct_.Name)
+						return err
+					}
+				}
+			}
+			for _, function := range pkg.Functions {
+				for _, call := range function.Calls {
+					if call.Package == "" {
+						continue
+					}
+					if err := UpsertFunctionCall(ctx, session, mod, function, call); err != nil {
+						log.Info().Err(err).Msgf("Error upserting call from %s to %s", function.Name, call.Callee)
+						return err
+					}
+				}
+			}
+			methods := make([]codesurgeon.Method, 0)
+			for _, struct_ := range pkg.Structs {
+				methods = append(methods, struct_.Methods...)
+			}
+			for _, typeDecl := range pkg.Types {
+				methods = append(methods, typeDecl.Methods...)
+			}
+			for _, method := range methods {
+				for _, call := range method.Calls {
+					if call.Package == "" {
+						continue
+					}
+					if err := UpsertMethodCall(ctx, session, mod, method, call); err != nil {
+						log.Info().Err(err).Msgf("Error upserting call from %s.%s to %s", method.ReceiverTypeName(), method.Name, call.Callee)
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
 // GenerateEmbeddings fetches documentation nodes, computes embeddings, and updates Neo4j.
 func GenerateEmbeddings(driver neo4j.DriverWithContext, openAIClient *openai.Client) error {
 
@@ -0,0 +1,242 @@
+package codesurgeon
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+)
+
+// parseCacheVersion is part of the keys of the parse cache. Bump it whenever the information
+// extracted from a file changes, so that the entries written by older versions aren't reused.
+const parseCacheVersion = 9
+
+// DefaultParseCacheDir is the directory of the parse cache, relative to the root of a module.
+const DefaultParseCacheDir = ".code-surgeon/cache"
+
+// ParseCache stores on disk the information extracted from each package directory, keyed by the
+// content of its files, so that parsing a tree again only parses the packages whose files changed.
+// A package is always extracted from all its files, so it's the same as when parsed without cache.
+// It is safe for concurrent use.
+type ParseCache struct {
+	dir    string
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// ParseCacheStats describes the content of a ParseCache.
+type ParseCacheStats struct {
+	Dir     string `json:"dir"`
+	Entries int    `json:"entries"`
+	Size    int64  `json:"size"`   // Size of the entries in bytes
+	Hits    int64  `json:"hits"`   // Package directories read from the cache since it was opened
+	Misses  int64  `json:"misses"` // Package directories parsed and stored since it was opened
+}
+
+// cachedDir is the entry of the parse cache for a package directory.
+type cachedDir struct {
+	Packages []Package `json:"packages"` // Packages of the directory, e.g. a package and its external test package
+}
+
+// OpenParseCache opens the parse cache stored in dir. The directory is only created when
+// the first entry is stored, so opening a cache to read its stats leaves the tree untouched.
+func OpenParseCache(dir string) (*ParseCache, error) {
+	return &ParseCache{dir: dir}, nil
+}
+
+// OpenModuleParseCache opens the parse cache of the module containing path, stored in
+// DefaultParseCacheDir at the root of the module.
+func OpenModuleParseCache(path string) (*ParseCache, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	mod, err := getModulePath(abs)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving module path: %w", err)
+	}
+	return OpenParseCache(filepath.Join(mod.Dir, DefaultParseCacheDir))
+}
+
+// Dir returns the directory of the cache.
+func (c *ParseCache) Dir() string {
+	return c.dir
+}
+
+// Stats returns the number and size of the entries of the cache, and the hits and misses since it was opened.
+func (c *ParseCache) Stats() (ParseCacheStats, error) {
+	stats := ParseCacheStats{
+		Dir:    c.dir,
+		Hits:   c.hits.Load(),
+		Misses: c.misses.Load(),
+	}
+	err := filepath.WalkDir(c.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == c.dir && errors.Is(err, fs.ErrNotExist) {
+				// Nothing was stored yet
+				return fs.SkipAll
+			}
+			return err
+		}
+		if d.IsDir() || filepath.Ext(p) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		stats.Entries++
+		stats.Size += info.Size()
+		return nil
+	})
+	return stats, err
+}
+
+// Clear removes every entry of the cache.
+func (c *ParseCache) Clear() error {
+	return os.RemoveAll(c.dir)
+}
+
+// parseDir parses the files of dir kept by filter like parsePackageDir does, reading them from src, and reuses the
+// extraction stored for dir when none of its files changed. With tolerant, files with syntax errors are parsed
+// like parseGoFile does, and the directories holding them aren't stored since they're usually being edited.
+func (c *ParseCache) parseDir(src sourceFS, dir string, filter func(fs.FileInfo) bool, mod *moduleRoot, tolerant bool) (*ParsedInfo, error) {
+	fileNames, err := src.goFiles(dir, filter)
+	if err != nil {
+		return nil, err
+	}
+	relPath, err := filepath.Rel(mod.Dir, dir)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving relative path: %w", err)
+	}
+	m := newModule(mod.Path, relPath, 1)
+
+	// The files are parsed as they were read for the key, even if they change in the meantime
+	pinned := sourceFS{fsys: src.fsys, overlay: make(map[string][]byte, len(src.overlay)+len(fileNames))}
+	for name, content := range src.overlay {
+		pinned.overlay[name] = content
+	}
+	h := c.newKey(dir, m.importPath())
+	for _, fileName := range fileNames {
+		content, err := src.readFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		pinned.overlay[fileName] = content
+		h.Write([]byte(fileName))
+		h.Write([]byte{0})
+		h.Write([]byte(strconv.Itoa(len(content))))
+		h.Write([]byte{0})
+		h.Write(content)
+	}
+	key := hex.EncodeToString(h.Sum(nil))
+
+	if entry, ok := c.load(key); ok {
+		c.hits.Add(1)
+		m.Packages = entry.Packages
+		for i := range m.Packages {
+			linkPackage(&m.Packages[i])
+		}
+		info := &ParsedInfo{
+			Modules:   []Module{*m},
+			Packages:  m.Packages,
+			Directory: dir,
+		}
+		ComputeMethodSets(info)
+		return info, nil
+	}
+
+	info, err := parsePackageDir(pinned, packageDir{dir: dir, mod: mod}, filter, nil, tolerant)
+	if err != nil {
+		return nil, err
+	}
+	c.misses.Add(1)
+	if len(info.Diagnostics) > 0 {
+		return info, nil
+	}
+	if err := c.store(key, cachedDir{Packages: info.Packages}); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// newKey starts the hash identifying the extraction of a package directory, to which the name and
+// content of each file are added. The paths of the directory and of its package are part of it
+// since they're recorded in positions and calls.
+func (c *ParseCache) newKey(dir string, pkgPath string) hash.Hash {
+	h := sha256.New()
+	h.Write([]byte(strconv.Itoa(parseCacheVersion)))
+	h.Write([]byte{0})
+	h.Write([]byte(dir))
+	h.Write([]byte{0})
+	h.Write([]byte(pkgPath))
+	h.Write([]byte{0})
+	return h
+}
+
+func (c *ParseCache) entryPath(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".json")
+}
+
+func (c *ParseCache) load(key string) (cachedDir, bool) {
+	data, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return cachedDir{}, false
+	}
+	var entry cachedDir
+	if err := json.Unmarshal(data, &entry); err != nil {
+		// Corrupted entries are parsed again and overwritten
+		return cachedDir{}, false
+	}
+	return entry, true
+}
+
+func (c *ParseCache) store(key string, entry cachedDir) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	entryPath := c.entryPath(key)
+	if err := os.MkdirAll(filepath.Dir(entryPath), 0755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so that concurrent readers never see a partial entry
+	tmp, err := os.CreateTemp(filepath.Dir(entryPath), key+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), entryPath)
+}
+
+// linkPackage sets the back-references to pkg and to the structs of pkg, which aren't stored in the cache.
+func linkPackage(pkg *Package) {
+	for i := range pkg.Structs {
+		s := &pkg.Structs[i]
+		s.PtrPackage = pkg
+		for j := range s.Methods {
+			s.Methods[j].PtrStruct = s
+		}
+	}
+	for i := range pkg.Interfaces {
+		pkg.Interfaces[i].PtrPackage = pkg
+	}
+	for i := range pkg.Types {
+		pkg.Types[i].PtrPackage = pkg
+	}
+}
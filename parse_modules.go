@@ -0,0 +1,244 @@
+package codesurgeon
+
+import (
+	"context"
+	"fmt"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// PackageError is an error met while parsing the package of a directory.
+type PackageError struct {
+	Directory string `json:"directory"`
+	Message   string `json:"message"`
+}
+
+func (e PackageError) Error() string {
+	return e.Directory + ": " + e.Message
+}
+
+// packageDir is a directory holding Go files and the module it belongs to.
+type packageDir struct {
+	dir string
+	mod *moduleRoot
+}
+
+// ParseModules parses every package under root with a pool of opts.Workers goroutines
+// (GOMAXPROCS by default) and returns one ParsedInfo per module, with one Module entry
+// per package directory. Nested modules (directories with their own go.mod) get their
+// own ParsedInfo. Files are kept like ParseDirectoryWithOptions does.
+// A package that fails to parse doesn't stop the others: its error is recorded in the
+// Errors of the ParsedInfo of its module, and with opts.Tolerant, syntax errors are recorded
+// in its Diagnostics instead. The returned error is only set when root can't be walked or ctx is done.
+func ParseModules(ctx context.Context, root string, opts ParseOptions) ([]*ParsedInfo, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	src := diskSource(opts.Overlay)
+	dirs, modules, err := findPackageDirs(src, root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Consolidate the packages of each module, in the order of the walk
+	byModule := make(map[*moduleRoot]*ParsedInfo, len(modules))
+	infos := make([]*ParsedInfo, 0, len(modules))
+	for _, mod := range modules {
+		info := &ParsedInfo{Directory: mod.Dir}
+		byModule[mod] = info
+		infos = append(infos, info)
+	}
+	err = parsePackageDirs(ctx, src, dirs, opts, func(i int, parsed *ParsedInfo, err error) error {
+		info := byModule[dirs[i].mod]
+		if err != nil {
+			info.Errors = append(info.Errors, PackageError{Directory: dirs[i].dir, Message: err.Error()})
+			return nil
+		}
+		info.Diagnostics = append(info.Diagnostics, parsed.Diagnostics...)
+		for _, m := range parsed.Modules {
+			if len(m.Packages) == 0 {
+				// Every file was left out by the build constraints
+				continue
+			}
+			info.Modules = append(info.Modules, m)
+			info.Packages = append(info.Packages, m.Packages...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ComputeMethodSets(infos...)
+	return infos, nil
+}
+
+// WalkPackages parses every package under root like ParseModules does, and calls fn with the
+// ParsedInfo of each package directory as soon as it's parsed, in the order of the walk, so that
+// large trees can be streamed without holding every package in memory. Method sets are computed
+// within each package, so methods promoted from other packages are left out. A package that
+// fails to parse is passed to fn with its error in Errors. Walking stops at the first error
+// returned by fn, which WalkPackages returns.
+func WalkPackages(ctx context.Context, root string, opts ParseOptions, fn func(info *ParsedInfo) error) error {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return err
+	}
+	src := diskSource(opts.Overlay)
+	dirs, _, err := findPackageDirs(src, root)
+	if err != nil {
+		return err
+	}
+	return parsePackageDirs(ctx, src, dirs, opts, func(i int, info *ParsedInfo, err error) error {
+		if err != nil {
+			info = &ParsedInfo{Directory: dirs[i].dir, Errors: []PackageError{{Directory: dirs[i].dir, Message: err.Error()}}}
+		} else if len(info.Packages) == 0 && len(info.Diagnostics) == 0 {
+			// Every file was left out by the build constraints
+			return nil
+		}
+		return fn(info)
+	})
+}
+
+// parsePackageDirs parses dirs with a pool of opts.Workers goroutines (GOMAXPROCS by default) and
+// calls emit with the result of each directory in the order of dirs, as soon as it's parsed.
+// Parsing stops at the first error returned by emit, or when ctx is done.
+func parsePackageDirs(ctx context.Context, src sourceFS, dirs []packageDir, opts ParseOptions, emit func(i int, info *ParsedInfo, err error) error) error {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	type result struct {
+		info *ParsedInfo
+		err  error
+	}
+	results := make([]result, len(dirs))
+	done := make([]chan struct{}, len(dirs))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	stop, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				info, err := parsePackageDir(src, dirs[j], opts.fileFilter(dirs[j].dir, src), opts.Cache, opts.Tolerant)
+				results[j] = result{info: info, err: err}
+				close(done[j])
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range dirs {
+			select {
+			case jobs <- i:
+			case <-stop.Done():
+				return
+			}
+		}
+	}()
+
+	var err error
+	for i := range dirs {
+		select {
+		case <-done[i]:
+		case <-stop.Done():
+		}
+		if stop.Err() != nil {
+			break
+		}
+		if err = emit(i, results[i].info, results[i].err); err != nil {
+			break
+		}
+		results[i] = result{} // Released once emitted
+	}
+	cancel()
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// findPackageDirs walks root and returns the directories holding Go files, on disk or in the overlay
+// of src, with their module, and the modules found. Like the go command, it skips testdata, vendor
+// and directories starting with . or _.
+func findPackageDirs(src sourceFS, root string) ([]packageDir, []*moduleRoot, error) {
+	rootModule, err := getModulePath(root)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error retrieving module path: %w", err)
+	}
+	modules := []*moduleRoot{rootModule}
+	moduleByDir := map[string]*moduleRoot{rootModule.Dir: rootModule}
+
+	var dirs []packageDir
+	err = src.walkDirs(root, func(p string) error {
+		if p != root && skipDir(filepath.Base(p)) {
+			return filepath.SkipDir
+		}
+
+		mod := moduleByDir[filepath.Dir(p)]
+		if p == root {
+			mod = rootModule
+		}
+		if _, err := os.Stat(filepath.Join(p, "go.mod")); err == nil && p != rootModule.Dir {
+			nested, err := readModuleRoot(p)
+			if err != nil {
+				return err
+			}
+			mod = nested
+			modules = append(modules, mod)
+		}
+		moduleByDir[p] = mod
+
+		fileNames, err := src.goFiles(p, nil)
+		if err != nil {
+			return err
+		}
+		if len(fileNames) > 0 {
+			dirs = append(dirs, packageDir{dir: p, mod: mod})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return dirs, modules, nil
+}
+
+// skipDir reports whether the go command ignores the directories named name when matching packages.
+func skipDir(name string) bool {
+	return name == "testdata" || name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")
+}
+
+// parsePackageDir parses the files of a directory kept by filter from src, through cache when it's not nil.
+func parsePackageDir(src sourceFS, dir packageDir, filter func(fs.FileInfo) bool, cache *ParseCache, tolerant bool) (*ParsedInfo, error) {
+	if cache != nil {
+		return cache.parseDir(src, dir.dir, filter, dir.mod, tolerant)
+	}
+	fset := token.NewFileSet()
+	packages, diagnostics, err := parseGoDir(fset, src, dir.dir, filter, tolerant)
+	if err != nil {
+		return nil, err
+	}
+	info, err := extractModuleParsedInfo(fset, packages, dir.mod, dir.dir)
+	if err != nil {
+		return nil, err
+	}
+	info.Directory = dir.dir
+	info.Diagnostics = diagnostics
+	return info, nil
+}
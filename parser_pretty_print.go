@@ -3,32 +3,42 @@ package codesurgeon
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 
 	"github.com/Knetic/govaluate"
 	"github.com/rs/zerolog/log"
 )
 
+// PrettyPrint formats parsed in the given mode. With tags, the text_long mode shows the struct tags
+// of the fields, only the tagKeys ones when there are any (e.g. "json", "db"). The llm mode only
+// shows them when tagKeys are given, AllTagKeys selecting every key.
 func PrettyPrint(
 	parsed []*ParsedInfo,
 	mode string,
 	ignoreRules []string,
 	plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, tags bool,
 	comments bool,
+	tagKeys ...string,
 ) string {
 	var sb strings.Builder
 
 	switch mode {
 	case "json":
 		return prettyPrintJSON(parsed)
+	case "jsonl":
+		if err := WriteJSONL(&sb, parsed...); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		return sb.String()
 	case "grepindex":
 		return prettyPrintGrepIndex(parsed, ignoreRules, &sb)
 	case "llm":
-		return prettyPrintLLM(parsed, ignoreRules, plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, comments, &sb)
+		return prettyPrintLLM(parsed, ignoreRules, plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, tags, comments, tagKeys, &sb)
 	case "text_short", "":
 		return prettyPrintTextShort(parsed, ignoreRules, plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, comments, &sb)
 	case "text_long":
-		return prettyPrintTextLong(parsed, plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, tags, comments, &sb)
+		return prettyPrintTextLong(parsed, plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, tags, comments, tagKeys, &sb)
 	default:
 		return "Invalid mode: " + mode
 	}
@@ -61,6 +71,8 @@ func prettyPrintGrepIndex(parsed []*ParsedInfo, ignoreRules []string, sb *string
 			processVariablesForGrepIndex(pkg, posType, posValue, sb)
 			processConstantsForGrepIndex(pkg, pkg_, sb)
 			processInterfacesForGrepIndex(pkg, pkg_, sb)
+			processTypesForGrepIndex(pkg, pkg_, sb)
+			processEnumsForGrepIndex(pkg, pkg_, sb)
 		}
 	}
 	return sb.String()
@@ -69,7 +81,8 @@ func prettyPrintGrepIndex(parsed []*ParsedInfo, ignoreRules []string, sb *string
 func prettyPrintLLM(
 	parsed []*ParsedInfo,
 	ignoreRules []string,
-	plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, comments bool,
+	plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, tags, comments bool,
+	tagKeys []string,
 	sb *strings.Builder,
 ) string {
 	for _, p := range parsed {
@@ -79,27 +92,33 @@ func prettyPrintLLM(
 		printPosition(p, sb)
 		for _, pkg := range p.Packages {
 			fmt.Fprintf(sb, "Package: %s\n", pkg.Package)
+			if synopsis := pkg.Synopsis(); comments && synopsis != "" {
+				fmt.Fprintf(sb, "  Synopsis: %s\n", synopsis)
+			}
 			for _, s := range pkg.Structs {
 				if shouldIgnoreStruct(s, pkg.Package, ignoreRules) || !shouldIncludeStruct(s, plainStructs, structsWithMethod) {
 					continue
 				}
-				fmt.Fprintf(sb, "  Struct: %s\n", s.Name)
+				fmt.Fprintf(sb, "  Struct: %s%s%s\n", s.Name, formatTypeParamList(s.TypeParams), formatEntityPosition(p, s.Position))
 				if comments && len(s.Docs) > 0 {
 					fmt.Fprintf(sb, "    Comment: %s\n", strings.Join(s.Docs, "\n"))
 				}
 				if fieldsStructsWithMethod || fieldsPlainStructs {
-					printFields(s.Fields, ignoreRules, sb, comments)
+					printFields(s.Fields, ignoreRules, sb, comments, tags, tagKeys)
 				}
 				if methods && len(s.Methods) > 0 {
-					printMethods(s.Methods, ignoreRules, sb)
+					printMethods(p, s.Methods, ignoreRules, sb)
+				}
+				if methods {
+					printPromotedMethods(s.MethodSet, sb)
 				}
 			}
 			if functions && len(pkg.Functions) > 0 {
-				printFunctions(pkg.Functions, ignoreRules, sb)
+				printFunctions(p, pkg.Functions, ignoreRules, sb)
 			}
 			// interfaces
 			for _, i := range pkg.Interfaces {
-				fmt.Fprintf(sb, "  Interface: %s\n", i.Name)
+				fmt.Fprintf(sb, "  Interface: %s%s%s\n", i.Name, formatTypeParamList(i.TypeParams), formatEntityPosition(p, i.Position))
 				if comments && len(i.Docs) > 0 {
 					fmt.Fprintf(sb, "    Comment: %s\n", strings.Join(i.Docs, "\n"))
 				}
@@ -113,14 +132,28 @@ func prettyPrintLLM(
 					}
 				}
 			}
+			// named types
+			for _, t := range pkg.Types {
+				fmt.Fprintf(sb, "  Type: %s%s%s\n", t.Name, formatTypeDeclUnderlying(t), formatEntityPosition(p, t.Position))
+				if comments && len(t.Docs) > 0 {
+					fmt.Fprintf(sb, "    Comment: %s\n", strings.Join(t.Docs, "\n"))
+				}
+				if methods && len(t.Methods) > 0 {
+					printMethods(p, t.Methods, ignoreRules, sb)
+				}
+			}
+			// enums
+			for _, e := range pkg.Enums {
+				fmt.Fprintf(sb, "  Enum: %s (%s)%s\n", e.Type, formatEnumMembers(e.Members), formatEntityPosition(p, e.Position))
+			}
 			// variables
 			for _, v := range pkg.Variables {
-				fmt.Fprintf(sb, "  Variable: %s (%s)\n", v.Name, v.Type)
+				fmt.Fprintf(sb, "  Variable: %s (%s)%s\n", v.Name, v.Type, formatEntityPosition(p, v.Position))
 			}
 
 			// constants
 			for _, c := range pkg.Constants {
-				fmt.Fprintf(sb, "  Constant: %s\n", c.Name)
+				fmt.Fprintf(sb, "  Constant: %s%s\n", c.Name, formatEntityPosition(p, c.Position))
 			}
 		}
 	}
@@ -182,6 +215,20 @@ func prettyPrintTextShort(
 					}
 				}
 			}
+			for _, t := range pkg.Types {
+				fmt.Fprintln(sb, "  Type:", t.Name)
+				if comments && len(t.Docs) > 0 {
+					fmt.Fprintf(sb, "    Comment: %s\n", strings.Join(t.Docs, "\n"))
+				}
+				if methods && len(t.Methods) > 0 {
+					for _, m := range t.Methods {
+						if shouldIgnoreMethod(m, ignoreRules) {
+							continue
+						}
+						fmt.Fprintln(sb, "    Method:", m.Name)
+					}
+				}
+			}
 		}
 	}
 	return sb.String()
@@ -190,6 +237,7 @@ func prettyPrintTextShort(
 func prettyPrintTextLong(
 	parsed []*ParsedInfo,
 	plainStructs, fieldsPlainStructs, structsWithMethod, fieldsStructsWithMethod, methods, functions, tags, comments bool,
+	tagKeys []string,
 	sb *strings.Builder,
 ) string {
 	for _, p := range parsed {
@@ -211,7 +259,11 @@ func prettyPrintTextLong(
 					for _, f := range s.Fields {
 						fmt.Fprintf(sb, "    Field: %s %s\n", f.Name, f.Type)
 						if f.Tag != "" && tags {
-							fmt.Fprintf(sb, "      Tag: %s\n", f.Tag)
+							if len(tagKeys) == 0 {
+								fmt.Fprintf(sb, "      Tag: %s\n", f.Tag)
+							} else if tag := formatTags(f.Tags, tagKeys); tag != "" {
+								fmt.Fprintf(sb, "      Tag: %s\n", tag)
+							}
 						}
 						if f.Comment != "" && comments {
 							fmt.Fprintf(sb, "      Comment: %s\n", f.Comment)
@@ -237,6 +289,31 @@ func prettyPrintTextLong(
 					}
 				}
 			}
+			for _, e := range pkg.Enums {
+				fmt.Fprintf(sb, "  Enum: %s\n", e.Type)
+				for _, m := range e.Members {
+					fmt.Fprintf(sb, "    Member: %s = %s\n", m.Name, formatEnumMemberValue(m))
+					if comments && len(m.Docs) > 0 {
+						fmt.Fprintf(sb, "      Comment: %s\n", strings.Join(m.Docs, "\n"))
+					}
+				}
+			}
+			for _, t := range pkg.Types {
+				fmt.Fprintf(sb, "  Type: %s\n", t.Name)
+				fmt.Fprintf(sb, "    Definition: %s\n", t.Definition)
+				if comments && len(t.Docs) > 0 {
+					fmt.Fprintf(sb, "    Comment: %s\n", strings.Join(t.Docs, "\n"))
+				}
+				if methods && len(t.Methods) > 0 {
+					for _, m := range t.Methods {
+						fmt.Fprintf(sb, "    Method: %s\n", m.Name)
+						fmt.Fprintf(sb, "      Signature: %s\n", m.Signature)
+						if comments && len(m.Docs) > 0 {
+							fmt.Fprintf(sb, "      Comment: %s\n", strings.Join(m.Docs, "\n"))
+						}
+					}
+				}
+			}
 		}
 	}
 	return sb.String()
@@ -274,6 +351,71 @@ func shouldIgnorePosition(p *ParsedInfo, ignoreRules []string, posType string) b
 	return false
 }
 
+// formatEntityPosition returns a short " (file:line)" reference to pos, relative to
+// the parsed directory when possible. It returns "" when the position is unknown.
+func formatEntityPosition(p *ParsedInfo, pos Position) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	file := pos.File
+	if file == "" {
+		return fmt.Sprintf(" (line %d)", pos.Line)
+	}
+	if p.Directory != "" {
+		if rel, err := filepath.Rel(p.Directory, file); err == nil && !strings.HasPrefix(rel, "..") {
+			file = rel
+		}
+	} else if p.File == file {
+		file = filepath.Base(file)
+	}
+	return fmt.Sprintf(" (%s:%d)", file, pos.Line)
+}
+
+// formatTypeParamList returns the "[K comparable, V any]" list of a generic entity, or "" when it has none.
+func formatTypeParamList(typeParams []TypeParam) string {
+	if len(typeParams) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(typeParams))
+	for _, tp := range typeParams {
+		parts = append(parts, strings.TrimSpace(tp.Name+" "+tp.Constraint))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// formatTypeDeclUnderlying returns " = pkg.Y" for aliases and " (string)" for other named types.
+func formatTypeDeclUnderlying(t TypeDecl) string {
+	if t.IsAlias {
+		return " = " + t.Underlying
+	}
+	return " (" + t.Underlying + ")"
+}
+
+// formatEnumMembers returns the "A=0, B=1" list of the members of an enum.
+func formatEnumMembers(members []EnumMember) string {
+	parts := make([]string, 0, len(members))
+	for _, m := range members {
+		parts = append(parts, m.Name+"="+formatEnumMemberValue(m))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// formatEnumMemberValue returns the resolved value of m, or its expression when it couldn't be resolved.
+func formatEnumMemberValue(m EnumMember) string {
+	if m.Value != "" {
+		return m.Value
+	}
+	return m.Expression
+}
+
+// formatGrepIndexPosition returns the "at file:line:column" token used by the grepindex format.
+func formatGrepIndexPosition(pos Position) string {
+	if !pos.IsValid() {
+		return ""
+	}
+	return "at " + pos.String() + " "
+}
+
 func formatAndPrint(sb *strings.Builder, text string) string {
 	fmt.Fprint(sb, text)
 	return strings.Replace(text, ">", ":", 1)
@@ -285,13 +427,13 @@ func processStructsForGrepIndex(pkg Package, pkg_ string, sb *strings.Builder) {
 		if len(s.Methods) == 0 {
 			hasMethods = "no_methods"
 		}
-		struct_ := formatAndPrint(sb, fmt.Sprintf("struct> %s %s %s", s.Name, hasMethods, pkg_))
+		struct_ := formatAndPrint(sb, fmt.Sprintf("struct> %s %s %s%s", s.Name, hasMethods, formatGrepIndexPosition(s.Position), pkg_))
 
 		for _, f := range s.Fields {
-			formatAndPrint(sb, fmt.Sprintf("field> %s (%s) %s", f.Name, f.Type, struct_))
+			formatAndPrint(sb, fmt.Sprintf("field> %s (%s) %s%s", f.Name, f.Type, formatGrepIndexPosition(f.Position), struct_))
 		}
 		for _, m := range s.Methods {
-			method_ := formatAndPrint(sb, fmt.Sprintf("method> %s %s", m.Name, struct_))
+			method_ := formatAndPrint(sb, fmt.Sprintf("method> %s %s%s", m.Name, formatGrepIndexPosition(m.Position), struct_))
 			paramsString := processParamsForGrepIndex(m.Params, method_, sb)
 			for _, r := range m.Returns {
 				formatAndPrint(sb, fmt.Sprintf("return> %s %s %s", r.Type, paramsString, method_))
@@ -314,7 +456,7 @@ func processParamsForGrepIndex(params []Param, method_ string, sb *strings.Build
 
 func processFunctionsForGrepIndex(pkg Package, pkg_ string, sb *strings.Builder) {
 	for _, f := range pkg.Functions {
-		function_ := formatAndPrint(sb, fmt.Sprintf("function> %s %s", f.Name, pkg_))
+		function_ := formatAndPrint(sb, fmt.Sprintf("function> %s %s%s", f.Name, formatGrepIndexPosition(f.Position), pkg_))
 		for _, p := range f.Params {
 			formatAndPrint(sb, fmt.Sprintf("param> %s (%s) %s", p.Name, p.Type, function_))
 		}
@@ -323,21 +465,21 @@ func processFunctionsForGrepIndex(pkg Package, pkg_ string, sb *strings.Builder)
 
 func processVariablesForGrepIndex(pkg Package, posType, posValue string, sb *strings.Builder) {
 	for _, v := range pkg.Variables {
-		formatAndPrint(sb, fmt.Sprintf("variable> %s (%s) package %s %s:%s", v.Name, v.Type, pkg.Package, posType, posValue))
+		formatAndPrint(sb, fmt.Sprintf("variable> %s (%s) %spackage %s %s:%s", v.Name, v.Type, formatGrepIndexPosition(v.Position), pkg.Package, posType, posValue))
 	}
 }
 
 func processConstantsForGrepIndex(pkg Package, pkg_ string, sb *strings.Builder) {
 	for _, c := range pkg.Constants {
-		formatAndPrint(sb, fmt.Sprintf("constant> %s %s", c.Name, pkg_))
+		formatAndPrint(sb, fmt.Sprintf("constant> %s %s%s", c.Name, formatGrepIndexPosition(c.Position), pkg_))
 	}
 }
 
 func processInterfacesForGrepIndex(pkg Package, pkg_ string, sb *strings.Builder) {
 	for _, i := range pkg.Interfaces {
-		interface_ := formatAndPrint(sb, fmt.Sprintf("interface> %s %s", i.Name, pkg_))
+		interface_ := formatAndPrint(sb, fmt.Sprintf("interface> %s %s%s", i.Name, formatGrepIndexPosition(i.Position), pkg_))
 		for _, m := range i.Methods {
-			method_ := formatAndPrint(sb, fmt.Sprintf("method> %s %s", m.Name, interface_))
+			method_ := formatAndPrint(sb, fmt.Sprintf("method> %s %s%s", m.Name, formatGrepIndexPosition(m.Position), interface_))
 			for _, p := range m.Params {
 				formatAndPrint(sb, fmt.Sprintf("param> %s (%s) %s", p.Name, p.Type, method_))
 			}
@@ -345,6 +487,32 @@ func processInterfacesForGrepIndex(pkg Package, pkg_ string, sb *strings.Builder
 	}
 }
 
+func processTypesForGrepIndex(pkg Package, pkg_ string, sb *strings.Builder) {
+	for _, t := range pkg.Types {
+		kind := "defined"
+		if t.IsAlias {
+			kind = "alias"
+		}
+		type_ := formatAndPrint(sb, fmt.Sprintf("type> %s (%s) %s %s%s", t.Name, t.Underlying, kind, formatGrepIndexPosition(t.Position), pkg_))
+		for _, m := range t.Methods {
+			method_ := formatAndPrint(sb, fmt.Sprintf("method> %s %s%s", m.Name, formatGrepIndexPosition(m.Position), type_))
+			paramsString := processParamsForGrepIndex(m.Params, method_, sb)
+			for _, r := range m.Returns {
+				formatAndPrint(sb, fmt.Sprintf("return> %s %s %s", r.Type, paramsString, method_))
+			}
+		}
+	}
+}
+
+func processEnumsForGrepIndex(pkg Package, pkg_ string, sb *strings.Builder) {
+	for _, e := range pkg.Enums {
+		enum_ := formatAndPrint(sb, fmt.Sprintf("enum> %s %s%s", e.Type, formatGrepIndexPosition(e.Position), pkg_))
+		for _, m := range e.Members {
+			formatAndPrint(sb, fmt.Sprintf("enum_member> %s = %s %s%s", m.Name, formatEnumMemberValue(m), formatGrepIndexPosition(m.Position), enum_))
+		}
+	}
+}
+
 func printPosition(p *ParsedInfo, sb *strings.Builder) {
 	if p.Directory != "" {
 		fmt.Fprintf(sb, "Directory: %s\n", p.Directory)
@@ -388,19 +556,40 @@ func shouldIncludeStruct(s Struct, plainStructs, structsWithMethod bool) bool {
 	return true
 }
 
-func printFields(fields []Field, ignoreRules []string, sb *strings.Builder, comments bool) {
+func printFields(fields []Field, ignoreRules []string, sb *strings.Builder, comments, tags bool, tagKeys []string) {
 	var fieldNames []string
 	for _, f := range fields {
 		if shouldIgnoreField(f, ignoreRules) {
 			continue
 		}
-		fieldNames = append(fieldNames, fmt.Sprintf("%s (%s)", f.Name, f.Type))
+		details := []string{f.Type}
+		if f.Embedded {
+			details = append(details, "embedded")
+		}
+		if tag := formatTags(f.Tags, tagKeys); tags && len(tagKeys) > 0 && tag != "" {
+			details = append(details, tag)
+		}
+		fieldNames = append(fieldNames, fmt.Sprintf("%s (%s)", f.Name, strings.Join(details, ", ")))
 	}
 	if len(fieldNames) > 0 {
 		fmt.Fprintf(sb, "    %s\n", strings.Join(fieldNames, ", "))
 	}
 }
 
+// printPromotedMethods lists the methods of a method set that are promoted from embedded fields.
+func printPromotedMethods(methodSet []MethodSetEntry, sb *strings.Builder) {
+	var promoted []string
+	for _, m := range methodSet {
+		if len(m.Via) == 0 {
+			continue
+		}
+		promoted = append(promoted, strings.Join(m.Via, ".")+"."+m.Signature)
+	}
+	if len(promoted) > 0 {
+		fmt.Fprintf(sb, "    Promoted methods: %s\n", strings.Join(promoted, ", "))
+	}
+}
+
 func shouldIgnoreField(f Field, ignoreRules []string) bool {
 	if len(ignoreRules) == 0 {
 		return false
@@ -424,13 +613,13 @@ func shouldIgnoreField(f Field, ignoreRules []string) bool {
 	return false
 }
 
-func printMethods(methods []Method, ignoreRules []string, sb *strings.Builder) {
+func printMethods(p *ParsedInfo, methods []Method, ignoreRules []string, sb *strings.Builder) {
 	var methodNamesAndSig []string
 	for _, m := range methods {
 		if shouldIgnoreMethod(m, ignoreRules) {
 			continue
 		}
-		methodNamesAndSig = append(methodNamesAndSig, m.Signature)
+		methodNamesAndSig = append(methodNamesAndSig, m.Signature+formatEntityPosition(p, m.Position))
 	}
 	if len(methodNamesAndSig) > 0 {
 		fmt.Fprintf(sb, "    Methods: %s\n", strings.Join(methodNamesAndSig, ", "))
@@ -461,13 +650,13 @@ func shouldIgnoreMethod(m Method, ignoreRules []string) bool {
 	return false
 }
 
-func printFunctions(functions []Function, ignoreRules []string, sb *strings.Builder) {
+func printFunctions(p *ParsedInfo, functions []Function, ignoreRules []string, sb *strings.Builder) {
 	var funcNames []string
 	for _, f := range functions {
 		if shouldIgnoreFunction(f, ignoreRules) {
 			continue
 		}
-		funcNames = append(funcNames, f.Name)
+		funcNames = append(funcNames, f.Name+formatTypeParamList(f.TypeParams)+formatEntityPosition(p, f.Position))
 	}
 	if len(funcNames) > 0 {
 		fmt.Fprintf(sb, "  Functions: %s\n", strings.Join(funcNames, ", "))
@@ -0,0 +1,144 @@
+package codesurgeon
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// FilePreview is the content a file would have once a change is applied, computed without writing it.
+type FilePreview struct {
+	File       string `json:"file"`
+	OldContent string `json:"old_content"` // Empty for new files
+	NewContent string `json:"new_content"`
+	Diff       string `json:"diff"`             // Unified diff from OldContent to NewContent, empty when the file doesn't change
+	IsNew      bool   `json:"is_new,omitempty"` // The file doesn't exist yet and would be created
+}
+
+// PreviewFileChanges is the dry-run variant of ApplyChangeSet: it returns the new content of each
+// file and the diff against its current content, sorted by file, without touching the disk.
+func PreviewFileChanges(changes []FileChange) ([]FilePreview, error) {
+	implementationsMap := make(map[string][]CodeFragment)
+	packageNames := make(map[string]string)
+	for _, change := range changes {
+		implementationsMap[change.File] = append(implementationsMap[change.File], change.Fragments...)
+		if packageNames[change.File] == "" {
+			// The first change naming the package of a file creates it
+			packageNames[change.File] = change.PackageName
+		}
+	}
+	return previewCodeFragments(implementationsMap, func(file string) string {
+		return packageNames[file]
+	})
+}
+
+// PreviewCodeFragments is PreviewFileChanges for the fragments of InsertCodeFragments, new files
+// being in package main.
+func PreviewCodeFragments(implementationsMap map[string][]CodeFragment) ([]FilePreview, error) {
+	return previewCodeFragments(implementationsMap, func(string) string {
+		return "main"
+	})
+}
+
+// previewCodeFragments computes the previews of the files of implementationsMap. Files that don't
+// exist are started with the package clause of packageName, like when they're created. Every
+// fragment is checked to leave its file parsable, and the error is a *ChangeSetError listing
+// all the fragments and files that failed.
+func previewCodeFragments(implementationsMap map[string][]CodeFragment, packageName func(file string) string) ([]FilePreview, error) {
+	files := make([]string, 0, len(implementationsMap))
+	for file := range implementationsMap {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	var failures []FragmentError
+	previews := make([]FilePreview, 0, len(files))
+	for _, file := range files {
+		preview := FilePreview{File: file}
+		content, err := os.ReadFile(file)
+		if errors.Is(err, fs.ErrNotExist) {
+			preview.IsNew = true
+			content = []byte("package " + packageName(file) + "\n")
+		} else if err != nil {
+			failures = append(failures, FragmentError{File: file, Fragment: -1, Err: err})
+			continue
+		} else {
+			preview.OldContent = string(content)
+		}
+
+		for i, fragment := range implementationsMap[file] {
+			updated, err := stageCodeFragment(file, content, fragment)
+			if err != nil {
+				failures = append(failures, FragmentError{File: file, Fragment: i, Err: err})
+				continue
+			}
+			content = updated
+		}
+		// The file is only formatted to check it, so that the code no fragment touched is left as it is
+		if _, err := format.Source(content); err != nil {
+			failures = append(failures, FragmentError{File: file, Fragment: -1, Err: err})
+			continue
+		}
+		preview.NewContent = string(content)
+
+		preview.Diff, err = unifiedDiff(file, preview.OldContent, preview.NewContent, preview.IsNew)
+		if err != nil {
+			return nil, err
+		}
+		previews = append(previews, preview)
+	}
+	if len(failures) > 0 {
+		return nil, &ChangeSetError{Failures: failures}
+	}
+	return previews, nil
+}
+
+// stageCodeFragment returns src with the operation of fragment applied, checking that the result
+// parses.
+func stageCodeFragment(filename string, src []byte, fragment CodeFragment) ([]byte, error) {
+	updated, err := applyCodeFragment(filename, src, fragment)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), filename, updated, parser.ParseComments); err != nil {
+		return nil, fmt.Errorf("fragment leaves the file unparsable: %w", err)
+	}
+	return updated, nil
+}
+
+// unifiedDiff returns the unified diff of a file, from /dev/null for new files.
+func unifiedDiff(file string, oldContent, newContent string, isNew bool) (string, error) {
+	if oldContent == newContent && !isNew {
+		return "", nil
+	}
+	path := strings.TrimPrefix(filepath.ToSlash(file), "/")
+	fromFile := "a/" + path
+	if isNew {
+		fromFile = "/dev/null"
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(oldContent),
+		B:        difflib.SplitLines(newContent),
+		FromFile: fromFile,
+		ToFile:   "b/" + path,
+		Context:  3,
+	})
+}
+
+// FormatPreviews concatenates the diffs of previews, as printed by a dry run.
+func FormatPreviews(previews []FilePreview) string {
+	var sb strings.Builder
+	for _, p := range previews {
+		sb.WriteString(p.Diff)
+	}
+	return sb.String()
+}
@@ -0,0 +1,105 @@
+package codesurgeon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewFileChanges(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "main.go")
+	original := "package main\n\nfunc Hello() string {\n\treturn \"hello\"\n}\n"
+	require.NoError(t, os.WriteFile(existing, []byte(original), 0644))
+	created := filepath.Join(dir, "util", "util.go")
+
+	changes := []FileChange{
+		{
+			PackageName: "main",
+			File:        existing,
+			Fragments:   []CodeFragment{{Content: "func Hello() string {\n\treturn \"hi\"\n}", Overwrite: true}},
+		},
+		{
+			PackageName: "util",
+			File:        created,
+			Fragments:   []CodeFragment{{Content: "func Add(a, b int) int { return a + b }"}},
+		},
+		{
+			PackageName: "main",
+			File:        existing,
+			Fragments:   []CodeFragment{{Content: "func Bye() {}"}},
+		},
+	}
+	previews, err := PreviewFileChanges(changes)
+	require.NoError(t, err)
+	require.Len(t, previews, 2)
+
+	// Nothing is written
+	content, err := os.ReadFile(existing)
+	require.NoError(t, err)
+	require.Equal(t, original, string(content))
+	require.NoFileExists(t, created)
+
+	main := previews[0]
+	require.Equal(t, existing, main.File)
+	require.False(t, main.IsNew)
+	require.Equal(t, original, main.OldContent)
+	require.Contains(t, main.NewContent, "return \"hi\"")
+	require.Contains(t, main.NewContent, "func Bye() {}")
+	require.Contains(t, main.Diff, "--- a"+existing+"\n+++ b"+existing+"\n")
+	require.Contains(t, main.Diff, "-\treturn \"hello\"\n+\treturn \"hi\"\n")
+	require.Contains(t, main.Diff, "+func Bye() {}\n")
+
+	util := previews[1]
+	require.True(t, util.IsNew)
+	require.Empty(t, util.OldContent)
+	require.Equal(t, "package util\n\nfunc Add(a, b int) int { return a + b }\n", util.NewContent)
+	require.Contains(t, util.Diff, "--- /dev/null\n+++ b"+created+"\n")
+	require.Equal(t, main.Diff+util.Diff, FormatPreviews(previews))
+
+	// The applied changes are the previewed ones
+	require.NoError(t, ApplyFileChanges(changes))
+	content, err = os.ReadFile(existing)
+	require.NoError(t, err)
+	require.Equal(t, main.NewContent, string(content))
+	content, err = os.ReadFile(created)
+	require.NoError(t, err)
+	require.Equal(t, util.NewContent, string(content))
+
+	// A failing change set is returned as is and nothing is written
+	err = ApplyFileChanges([]FileChange{{File: existing, Fragments: []CodeFragment{{Content: "func {"}}}})
+	var changeSetErr *ChangeSetError
+	require.ErrorAs(t, err, &changeSetErr)
+	content, err = os.ReadFile(existing)
+	require.NoError(t, err)
+	require.Equal(t, main.NewContent, string(content))
+
+	// The first change naming the package of a new file creates it
+	previews, err = PreviewFileChanges([]FileChange{
+		{PackageName: "first", File: filepath.Join(dir, "pkg", "pkg.go"), Fragments: []CodeFragment{{Content: "func A() {}"}}},
+		{File: filepath.Join(dir, "pkg", "pkg.go"), Fragments: []CodeFragment{{Content: "func B() {}"}}},
+		{PackageName: "second", File: filepath.Join(dir, "pkg", "pkg.go")},
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(previews[0].NewContent, "package first\n"))
+
+	// Unchanged files have no diff
+	previews, err = PreviewCodeFragments(map[string][]CodeFragment{existing: {{Content: "func Bye() {}"}}})
+	require.NoError(t, err)
+	require.Empty(t, previews[0].Diff)
+}
+
+func TestPreviewFileChanges_KeepsFormatting(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.go")
+	original := "package main\n\nvar x=1\n\nfunc  Old( ) {  }\n"
+	require.NoError(t, os.WriteFile(file, []byte(original), 0644))
+
+	previews, err := PreviewFileChanges([]FileChange{{File: file, Fragments: []CodeFragment{{Content: "func New() {}"}}}})
+	require.NoError(t, err)
+	require.Equal(t, original+"\nfunc New() {}\n", previews[0].NewContent, "code no fragment touched isn't reformatted")
+	require.NotContains(t, previews[0].Diff, "\n-", "no line is removed")
+}
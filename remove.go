@@ -0,0 +1,294 @@
+package codesurgeon
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// DeclSelector selects a top-level declaration of a file: a function, a type, a constant or a
+// variable by Name, or a method by Receiver and Name.
+type DeclSelector struct {
+	Receiver string `json:"receiver,omitempty"` // Receiver type of a method, without pointer or type parameters, e.g. List for func (l *List[T]) Len()
+	Name     string `json:"name"`
+}
+
+func (s DeclSelector) String() string {
+	if s.Receiver != "" {
+		return s.Receiver + "." + s.Name
+	}
+	return s.Name
+}
+
+// RemoveDeclarations removes the declarations selected by selectors from file, with their doc
+// and line comments, and the imports that only they used. A constant or variable declared in a
+// group is removed from the group. It fails, leaving the file as is, when a selector matches no
+// declaration. It's a change set of a single OperationRemove fragment, see ApplyChangeSet.
+func RemoveDeclarations(file string, selectors []DeclSelector) error {
+	_, err := ApplyChangeSet([]FileChange{{
+		File:      file,
+		Fragments: []CodeFragment{{Operation: OperationRemove, Selectors: selectors}},
+	}})
+	return err
+}
+
+// removal is a top-level declaration to remove, or some of the specs of a GenDecl.
+type removal struct {
+	decl  ast.Decl
+	specs []ast.Spec // Specs to remove, the whole declaration when nil
+}
+
+// removeDeclarations returns src without the declarations selected by selectors, and without the
+// imports that only they used.
+func removeDeclarations(filename string, src []byte, selectors []DeclSelector) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+
+	var removals []*removal
+	byDecl := make(map[ast.Decl]*removal)
+	for _, selector := range selectors {
+		decl, spec, err := selectDeclaration(file, selector)
+		if err != nil {
+			return nil, err
+		}
+		r, ok := byDecl[decl]
+		if !ok {
+			r = &removal{decl: decl}
+			byDecl[decl] = r
+			removals = append(removals, r)
+		}
+		if spec != nil && !slices.Contains(r.specs, spec) {
+			r.specs = append(r.specs, spec)
+		}
+	}
+	for _, r := range removals {
+		if gd, ok := r.decl.(*ast.GenDecl); ok {
+			if err := checkConstRemoval(gd, r.specs); err != nil {
+				return nil, err
+			}
+			if len(r.specs) == len(gd.Specs) {
+				r.specs = nil
+			}
+		}
+	}
+
+	removedUses := make(map[string]bool)
+	for _, r := range removals {
+		if r.specs == nil {
+			collectPackageUses(r.decl, removedUses)
+		}
+		for _, spec := range r.specs {
+			collectPackageUses(spec, removedUses)
+		}
+	}
+	src = removeRanges(fset, file, src, removals)
+
+	return removeUnusedImports(filename, src, removedUses)
+}
+
+// selectDeclaration returns the declaration of file selected by selector, and its spec for
+// types, constants and variables.
+func selectDeclaration(file *ast.File, selector DeclSelector) (ast.Decl, ast.Spec, error) {
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Name.Name == selector.Name && getReceiverType(d) == selector.Receiver {
+				return d, nil, nil
+			}
+		case *ast.GenDecl:
+			if selector.Receiver != "" || d.Tok == token.IMPORT {
+				continue
+			}
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.Name == selector.Name {
+						return d, s, nil
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.Name != selector.Name {
+							continue
+						}
+						if len(s.Names) > 1 {
+							return nil, nil, fmt.Errorf("cannot remove %s: it's declared together with other names", selector)
+						}
+						return d, s, nil
+					}
+				}
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("declaration %s not found", selector)
+}
+
+// checkConstRemoval fails when removing specs from a const block would change the constants
+// that follow them, which implicitly repeat the expression of the previous constant, e.g. iota.
+func checkConstRemoval(gd *ast.GenDecl, specs []ast.Spec) error {
+	if gd.Tok != token.CONST || specs == nil {
+		return nil
+	}
+	removed := make(map[ast.Spec]bool, len(specs))
+	for _, spec := range specs {
+		removed[spec] = true
+	}
+	for i, spec := range gd.Specs {
+		if !removed[spec] || len(spec.(*ast.ValueSpec).Values) == 0 {
+			continue
+		}
+		for _, next := range gd.Specs[i+1:] {
+			vs := next.(*ast.ValueSpec)
+			if len(vs.Values) > 0 {
+				break
+			}
+			if !removed[next] {
+				return fmt.Errorf("cannot remove %s: %s repeats its value", spec.(*ast.ValueSpec).Names[0].Name, vs.Names[0].Name)
+			}
+		}
+	}
+	return nil
+}
+
+// removeRanges returns src without removals, with their comments and the lines they leave empty.
+func removeRanges(fset *token.FileSet, file *ast.File, src []byte, removals []*removal) []byte {
+	var ranges [][2]int
+	for _, r := range removals {
+		if r.specs == nil {
+			start, end := declRange(fset, file, r.decl)
+			ranges = append(ranges, lineRange(src, start, end))
+			continue
+		}
+		for _, spec := range r.specs {
+			start, end := specRange(fset, spec)
+			ranges = append(ranges, lineRange(src, start, end))
+		}
+	}
+	// From the end, so that the offsets of the other ranges stay right
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] > ranges[j][0] })
+	for _, r := range ranges {
+		src = splice(src, r[0], r[1], "")
+	}
+	return src
+}
+
+// specRange returns the offsets of a spec, from its doc comment to its line comment.
+func specRange(fset *token.FileSet, spec ast.Spec) (int, int) {
+	start, end := spec.Pos(), spec.End()
+	var doc, comment *ast.CommentGroup
+	switch s := spec.(type) {
+	case *ast.TypeSpec:
+		doc, comment = s.Doc, s.Comment
+	case *ast.ValueSpec:
+		doc, comment = s.Doc, s.Comment
+	case *ast.ImportSpec:
+		doc, comment = s.Doc, s.Comment
+	}
+	if doc != nil {
+		start = doc.Pos()
+	}
+	if comment != nil {
+		end = comment.End()
+	}
+	return fset.Position(start).Offset, fset.Position(end).Offset
+}
+
+// lineRange extends the range from start to end to the whole lines it spans, when nothing else
+// is on them.
+func lineRange(src []byte, start, end int) [2]int {
+	lineStart := start
+	for lineStart > 0 && (src[lineStart-1] == ' ' || src[lineStart-1] == '\t') {
+		lineStart--
+	}
+	lineEnd := end
+	for lineEnd < len(src) && (src[lineEnd] == ' ' || src[lineEnd] == '\t') {
+		lineEnd++
+	}
+	if (lineStart == 0 || src[lineStart-1] == '\n') && (lineEnd == len(src) || src[lineEnd] == '\n') {
+		if lineEnd < len(src) {
+			lineEnd++
+		}
+		return [2]int{lineStart, lineEnd}
+	}
+	return [2]int{start, end}
+}
+
+// collectPackageUses adds to uses the identifiers that node uses as package names, e.g. fmt in
+// fmt.Println. Identifiers resolved to an object declared in the file, like a local variable
+// named after a package, aren't package names. Those declared in other files of the package
+// can't be told apart and are added too.
+func collectPackageUses(node ast.Node, uses map[string]bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok && ident.Obj == nil {
+				uses[ident.Name] = true
+			}
+		}
+		return true
+	})
+}
+
+// removeUnusedImports removes from src the imports used by the removed declarations, removedUses,
+// that aren't used anymore. Other imports are left as is, even if unused.
+func removeUnusedImports(filename string, src []byte, removedUses map[string]bool) ([]byte, error) {
+	if len(removedUses) == 0 {
+		return src, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+
+	uses := make(map[string]bool)
+	for _, decl := range file.Decls {
+		if gd, ok := decl.(*ast.GenDecl); !ok || gd.Tok != token.IMPORT {
+			collectPackageUses(decl, uses)
+		}
+	}
+
+	var removals []*removal
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		r := &removal{decl: gd}
+		for _, spec := range gd.Specs {
+			name := importSpecName(spec.(*ast.ImportSpec))
+			if removedUses[name] && !uses[name] {
+				r.specs = append(r.specs, spec)
+			}
+		}
+		if len(r.specs) == 0 {
+			continue
+		}
+		if len(r.specs) == len(gd.Specs) {
+			r.specs = nil
+		}
+		removals = append(removals, r)
+	}
+	return removeRanges(fset, file, src, removals), nil
+}
+
+// importSpecName returns the name an import is used with, its explicit name or the one guessed
+// from its path. Blank, dot and cgo imports have no name.
+func importSpecName(imp *ast.ImportSpec) string {
+	if imp.Name != nil {
+		if imp.Name.Name == "_" || imp.Name.Name == "." {
+			return ""
+		}
+		return imp.Name.Name
+	}
+	path, err := strconv.Unquote(imp.Path.Value)
+	if err != nil || path == "C" {
+		return ""
+	}
+	return importName(path)
+}
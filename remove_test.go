@@ -0,0 +1,180 @@
+package codesurgeon
+
+import (
+	"go/format"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const removeSrc = `package shop
+
+import (
+	"fmt"
+	"strings"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// Limit is the maximum number of items.
+const Limit = 10
+
+const (
+	// Small size.
+	Small = iota
+	Medium
+	Large
+)
+
+var (
+	// Default cart.
+	Default = &List[string]{} // shared
+	empty   = strings.TrimSpace(" ")
+)
+
+// List holds items.
+type List[T any] struct {
+	items []T
+}
+
+// Len returns the number of items.
+func (l *List[T]) Len() int { return len(l.items) }
+
+// Print prints the list.
+func (l *List[T]) Print() {
+	fmt.Println(l.items)
+}
+
+// Len returns the length of s.
+func Len(s string) int { return len(s) }
+
+func Dump(v any) ([]byte, error) {
+	return yaml.Marshal(v)
+}
+`
+
+func TestRemoveDeclarations(t *testing.T) {
+	result, err := removeDeclarations("shop.go", []byte(removeSrc), []DeclSelector{
+		{Receiver: "List", Name: "Print"},
+		{Name: "Dump"},
+		{Name: "Limit"},
+		{Name: "Default"},
+		{Name: "Default"},
+	})
+	require.NoError(t, err)
+	result, err = format.Source(result)
+	require.NoError(t, err)
+	require.Equal(t, `package shop
+
+import (
+	"strings"
+)
+
+const (
+	// Small size.
+	Small = iota
+	Medium
+	Large
+)
+
+var (
+	empty = strings.TrimSpace(" ")
+)
+
+// List holds items.
+type List[T any] struct {
+	items []T
+}
+
+// Len returns the number of items.
+func (l *List[T]) Len() int { return len(l.items) }
+
+// Len returns the length of s.
+func Len(s string) int { return len(s) }
+`, string(result))
+
+	// Removing all the specs of a group removes the group, and the method is told apart from the function
+	result, err = removeDeclarations("shop.go", []byte(removeSrc), []DeclSelector{{Name: "Small"}, {Name: "Medium"}, {Name: "Large"}, {Name: "Len"}})
+	require.NoError(t, err)
+	require.NotContains(t, string(result), "const (")
+	require.NotContains(t, string(result), "func Len(")
+	require.Contains(t, string(result), "func (l *List[T]) Len() int")
+
+	_, err = removeDeclarations("shop.go", []byte(removeSrc), []DeclSelector{{Name: "Missing"}})
+	require.EqualError(t, err, "declaration Missing not found")
+	_, err = removeDeclarations("shop.go", []byte(removeSrc), []DeclSelector{{Receiver: "List", Name: "Missing"}})
+	require.EqualError(t, err, "declaration List.Missing not found")
+	// Medium and Large repeat iota
+	_, err = removeDeclarations("shop.go", []byte(removeSrc), []DeclSelector{{Name: "Small"}})
+	require.EqualError(t, err, "cannot remove Small: Medium repeats its value")
+}
+
+func TestRemoveDeclarations_UnusedImports(t *testing.T) {
+	src := `package shop
+
+import (
+	"strings"
+
+	"github.com/x/go-foo"
+	"gopkg.in/yaml.v3"
+)
+
+type Builder struct{}
+
+func (Builder) Reset() {}
+
+// Keep has a parameter named after a package.
+func Keep(strings Builder) { strings.Reset() }
+
+func Dump(v any) ([]byte, error) {
+	foo.Check(v)
+	return yaml.Marshal(strings.TrimSpace(""))
+}
+`
+	result, err := removeDeclarations("shop.go", []byte(src), []DeclSelector{{Name: "Dump"}})
+	require.NoError(t, err)
+	result, err = format.Source(result)
+	require.NoError(t, err)
+	require.Equal(t, `package shop
+
+type Builder struct{}
+
+func (Builder) Reset() {}
+
+// Keep has a parameter named after a package.
+func Keep(strings Builder) { strings.Reset() }
+`, string(result), "versioned and go- paths are matched, and locals don't use imports")
+}
+
+func TestRemoveDeclarationsInChangeSet(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "shop.go")
+	require.NoError(t, os.WriteFile(file, []byte(removeSrc), 0644))
+
+	// Inserts and removals mixed in one change set
+	_, err := ApplyChangeSet([]FileChange{{
+		File: file,
+		Fragments: []CodeFragment{
+			{Operation: OperationRemove, Selectors: []DeclSelector{{Name: "Dump"}, {Receiver: "List", Name: "Print"}}},
+			{Content: "import \"fmt\"\n\n// Print prints the list.\nfunc (l *List[T]) Print() {\n\tfmt.Println(len(l.items))\n}", Overwrite: true},
+		},
+	}})
+	require.NoError(t, err)
+	content, err := os.ReadFile(file)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "yaml")
+	require.Contains(t, string(content), "\t\"fmt\"\n")
+	require.Contains(t, string(content), "fmt.Println(len(l.items))")
+
+	require.NoError(t, RemoveDeclarations(file, []DeclSelector{{Name: "List"}}))
+	content, err = os.ReadFile(file)
+	require.NoError(t, err)
+	require.NotContains(t, string(content), "type List")
+
+	err = RemoveDeclarations(file, []DeclSelector{{Name: "List"}})
+	require.ErrorContains(t, err, "declaration List not found")
+
+	_, err = ApplyChangeSet([]FileChange{{File: file, Fragments: []CodeFragment{{Operation: "rename"}}}})
+	require.ErrorContains(t, err, `unknown fragment operation "rename"`)
+}
@@ -0,0 +1,252 @@
+package codesurgeon
+
+import (
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+)
+
+// Render returns the formatted Go source of a declaration of the model: a Struct, Interface,
+// TypeDecl, Function, Method, Variable or Constant, or a pointer to one of them. It's the reverse
+// of parsing, so that a parsed declaration can be modified (e.g. adding a field, changing a tag
+// or adding a param) and written back with InsertCodeFragments.
+// Docs and Directives are rendered as comments, Definition and Signature are ignored.
+func Render(decl any) (string, error) {
+	var sb strings.Builder
+	switch d := decl.(type) {
+	case Struct:
+		renderStruct(&sb, d)
+	case *Struct:
+		renderStruct(&sb, *d)
+	case Interface:
+		renderInterface(&sb, d)
+	case *Interface:
+		renderInterface(&sb, *d)
+	case TypeDecl:
+		renderTypeDecl(&sb, d)
+	case *TypeDecl:
+		renderTypeDecl(&sb, *d)
+	case Function:
+		renderFunction(&sb, d)
+	case *Function:
+		renderFunction(&sb, *d)
+	case Method:
+		renderMethod(&sb, d)
+	case *Method:
+		renderMethod(&sb, *d)
+	case Variable:
+		renderVariable(&sb, d)
+	case *Variable:
+		renderVariable(&sb, *d)
+	case Constant:
+		if err := renderConstant(&sb, d); err != nil {
+			return "", err
+		}
+	case *Constant:
+		if err := renderConstant(&sb, *d); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported declaration: %T", decl)
+	}
+
+	formatted, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return "", fmt.Errorf("error formatting rendered declaration: %w\n%s", err, sb.String())
+	}
+	return string(formatted), nil
+}
+
+// RenderFragment renders decls with Render into a single CodeFragment, to insert them with
+// InsertCodeFragments or ApplyFileChanges.
+func RenderFragment(overwrite bool, decls ...any) (CodeFragment, error) {
+	parts := make([]string, 0, len(decls))
+	for _, decl := range decls {
+		src, err := Render(decl)
+		if err != nil {
+			return CodeFragment{}, err
+		}
+		parts = append(parts, src)
+	}
+	return CodeFragment{Content: strings.Join(parts, "\n"), Overwrite: overwrite}, nil
+}
+
+func renderStruct(sb *strings.Builder, s Struct) {
+	trailing := renderDocs(sb, "", s.Docs, s.Directives, s.Position.Line)
+	fmt.Fprintf(sb, "type %s%s struct {\n", s.Name, formatTypeParamList(s.TypeParams))
+	for _, f := range s.Fields {
+		fieldTrailing := renderDocs(sb, "\t", f.Docs, f.Directives, f.Position.Line)
+		sb.WriteString("\t")
+		if !f.Embedded {
+			sb.WriteString(strings.Join(fieldNames(f), ", ") + " ")
+		}
+		sb.WriteString(f.Type)
+		if tag := renderTag(f); tag != "" {
+			sb.WriteString(" " + tag)
+		}
+		if f.Comment != "" {
+			fieldTrailing = append([]string{"// " + f.Comment}, fieldTrailing...)
+		}
+		renderTrailing(sb, fieldTrailing)
+	}
+	sb.WriteString("}")
+	renderTrailing(sb, trailing)
+}
+
+func renderInterface(sb *strings.Builder, i Interface) {
+	trailing := renderDocs(sb, "", i.Docs, i.Directives, i.Position.Line)
+	fmt.Fprintf(sb, "type %s%s interface {\n", i.Name, formatTypeParamList(i.TypeParams))
+	for _, embed := range i.Embeds {
+		fmt.Fprintf(sb, "\t%s\n", embed)
+	}
+	for _, union := range i.Unions {
+		terms := make([]string, 0, len(union))
+		for _, term := range union {
+			if term.Tilde {
+				terms = append(terms, "~"+term.Type)
+			} else {
+				terms = append(terms, term.Type)
+			}
+		}
+		fmt.Fprintf(sb, "\t%s\n", strings.Join(terms, " | "))
+	}
+	for _, m := range i.Methods {
+		methodTrailing := renderDocs(sb, "\t", m.Docs, m.Directives, m.Position.Line)
+		fmt.Fprintf(sb, "\t%s%s", m.Name, renderSignature(m.Params, m.Returns))
+		renderTrailing(sb, methodTrailing)
+	}
+	sb.WriteString("}")
+	renderTrailing(sb, trailing)
+}
+
+func renderTypeDecl(sb *strings.Builder, t TypeDecl) {
+	trailing := renderDocs(sb, "", t.Docs, t.Directives, t.Position.Line)
+	assign := ""
+	if t.IsAlias {
+		assign = "= "
+	}
+	fmt.Fprintf(sb, "type %s%s %s%s", t.Name, formatTypeParamList(t.TypeParams), assign, t.Underlying)
+	renderTrailing(sb, trailing)
+}
+
+func renderFunction(sb *strings.Builder, f Function) {
+	renderDocs(sb, "", f.Docs, f.Directives, 0)
+	fmt.Fprintf(sb, "func %s%s%s %s\n", f.Name, formatTypeParamList(f.TypeParams), renderSignature(f.Params, f.Returns), renderBody(f.Body))
+}
+
+func renderMethod(sb *strings.Builder, m Method) {
+	renderDocs(sb, "", m.Docs, m.Directives, 0)
+	receiver := strings.TrimSpace(m.ReceiverName + " " + m.Receiver)
+	fmt.Fprintf(sb, "func (%s) %s%s %s\n", receiver, m.Name, renderSignature(m.Params, m.Returns), renderBody(m.Body))
+}
+
+func renderVariable(sb *strings.Builder, v Variable) {
+	trailing := renderDocs(sb, "", v.Docs, v.Directives, v.Position.Line)
+	sb.WriteString("var " + v.Name)
+	if v.Type != "" {
+		sb.WriteString(" " + v.Type)
+	}
+	if v.Value != "" {
+		sb.WriteString(" = " + v.Value)
+	}
+	renderTrailing(sb, trailing)
+}
+
+func renderConstant(sb *strings.Builder, c Constant) error {
+	if c.Value == "" {
+		// Constants of a block repeating the previous expression, e.g. iota, have no value of their own
+		return fmt.Errorf("constant %s has no value", c.Name)
+	}
+	trailing := renderDocs(sb, "", c.Docs, c.Directives, c.Position.Line)
+	sb.WriteString("const " + c.Name)
+	if c.Type != "" {
+		sb.WriteString(" " + c.Type)
+	}
+	sb.WriteString(" = " + c.Value)
+	renderTrailing(sb, trailing)
+	return nil
+}
+
+// renderDocs writes the doc comment of a declaration, with its directives, and returns the
+// directives written on the same line as the declaration, at line, to write after it.
+func renderDocs(sb *strings.Builder, indent string, docs []string, directives []Directive, line int) []string {
+	for _, doc := range docs {
+		for _, l := range strings.Split(doc, "\n") {
+			fmt.Fprintf(sb, "%s%s\n", indent, strings.TrimSpace("// "+l))
+		}
+	}
+	var trailing []string
+	for i, d := range directives {
+		if line > 0 && d.Position.Line == line {
+			trailing = append(trailing, renderDirective(d))
+			continue
+		}
+		if i == 0 && len(docs) > 0 {
+			// Directives are separated from the doc comment so that they don't show up in go doc
+			fmt.Fprintf(sb, "%s//\n", indent)
+		}
+		fmt.Fprintf(sb, "%s%s\n", indent, renderDirective(d))
+	}
+	return trailing
+}
+
+// renderTrailing ends the line of a declaration with its line comments.
+func renderTrailing(sb *strings.Builder, comments []string) {
+	if len(comments) > 0 {
+		sb.WriteString(" " + strings.Join(comments, " "))
+	}
+	sb.WriteString("\n")
+}
+
+// renderDirective returns a directive as written in the source, the reverse of parseDirective.
+func renderDirective(d Directive) string {
+	switch {
+	case d.Name == "+build":
+		return "// +build " + d.Args
+	case d.Name == "nolint" && d.Args != "":
+		return "//nolint:" + d.Args
+	}
+	return strings.TrimSpace("//" + d.Name + " " + d.Args)
+}
+
+// renderTag returns the tag of a field with its quotes, built from Tags when Tag isn't set.
+func renderTag(f Field) string {
+	tag := f.Tag
+	if tag == "" {
+		tag = formatTags(f.Tags, nil)
+	}
+	if tag == "" {
+		return ""
+	}
+	if strings.Contains(tag, "`") {
+		return strconv.Quote(tag)
+	}
+	return "`" + tag + "`"
+}
+
+// renderSignature returns the parameters and results of a function, e.g. "(a int, b string) error".
+func renderSignature(params []Param, returns []Param) string {
+	list := func(params []Param) string {
+		parts := make([]string, 0, len(params))
+		for _, p := range params {
+			parts = append(parts, strings.TrimSpace(p.Name+" "+p.Type))
+		}
+		return strings.Join(parts, ", ")
+	}
+	signature := "(" + list(params) + ")"
+	if len(returns) == 1 && returns[0].Name == "" {
+		signature += " " + returns[0].Type
+	} else if len(returns) > 0 {
+		signature += " (" + list(returns) + ")"
+	}
+	return signature
+}
+
+// renderBody returns the body of a function, an empty block when it has none.
+func renderBody(body string) string {
+	if strings.TrimSpace(body) == "" {
+		return "{\n}"
+	}
+	return body
+}
@@ -0,0 +1,134 @@
+package codesurgeon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	code := `package test
+
+// Cart holds items.
+//
+//nolint:revive
+type Cart[K comparable] struct {
+	Items map[K]int ` + "`json:\"items\" db:\"items\"`" + `
+	// Owner of the cart.
+	Owner string //nolint:lll
+	Store
+}
+
+type Store interface {
+	io.Reader
+	// Get returns a cart.
+	Get(id string) (*Cart[string], error)
+}
+
+type Number interface {
+	~int | ~float64
+}
+
+type Status = string
+
+// Add adds n of item.
+func (c *Cart[K]) Add(item K, n int) {
+	c.Items[item] += n
+}
+
+//go:noinline
+func Sum(values ...int) (total int, err error) {
+	for _, v := range values {
+		total += v
+	}
+	return total, nil
+}
+
+var Default = &Cart[string]{}
+
+const Limit int = 10
+`
+	parsed, err := ParseString(code)
+	require.NoError(t, err)
+	pkg := parsed.Packages[0]
+	h := newHelper(&pkg)
+
+	cart := h.Struct("Cart")
+	src, err := Render(cart.Struct)
+	require.NoError(t, err)
+	require.Equal(t, `// Cart holds items.
+//
+//nolint:revive
+type Cart[K comparable] struct {
+	Items map[K]int `+"`json:\"items\" db:\"items\"`"+`
+	// Owner of the cart.
+	Owner string //nolint:lll
+	Store
+}
+`, src)
+
+	// Edit the model: add a field and change a tag
+	cart.Struct.Fields[0].SetTag("json", "items,omitempty")
+	cart.Struct.Fields = append(cart.Struct.Fields, Field{Name: "Total", Type: "int", Tags: []Tag{{Key: "json", Value: "total"}}})
+	src, err = Render(&cart.Struct)
+	require.NoError(t, err)
+	require.Contains(t, src, "\tItems map[K]int `json:\"items,omitempty\" db:\"items\"`\n")
+	require.Contains(t, src, "\tTotal int `json:\"total\"`\n}\n")
+
+	store := h.Interface("Store")
+	src, err = Render(store)
+	require.NoError(t, err)
+	require.Equal(t, "type Store interface {\n\tio.Reader\n\t// Get returns a cart.\n\tGet(id string) (*Cart[string], error)\n}\n", src)
+
+	src, err = Render(h.Interface("Number"))
+	require.NoError(t, err)
+	require.Equal(t, "type Number interface {\n\t~int | ~float64\n}\n", src)
+
+	src, err = Render(pkg.Types[0])
+	require.NoError(t, err)
+	require.Equal(t, "type Status = string\n", src)
+
+	add := cart.Struct.Methods[0]
+	src, err = Render(add)
+	require.NoError(t, err)
+	require.Equal(t, "// Add adds n of item.\nfunc (c *Cart[K]) Add(item K, n int) {\n\tc.Items[item] += n\n}\n", src)
+
+	// Edit the model: add a param
+	sum := h.Function("Sum")
+	sum.Params = append([]Param{{Name: "base", Type: "int"}}, sum.Params...)
+	src, err = Render(sum)
+	require.NoError(t, err)
+	require.Contains(t, src, "//go:noinline\nfunc Sum(base int, values ...int) (total int, err error) {\n")
+
+	src, err = Render(h.Variable("Default"))
+	require.NoError(t, err)
+	require.Equal(t, "var Default = &Cart[string]{}\n", src)
+
+	src, err = Render(h.Constant("Limit"))
+	require.NoError(t, err)
+	require.Equal(t, "const Limit int = 10\n", src)
+
+	src, err = Render(Function{Name: "New", Returns: []Param{{Type: "*Cart[string]"}}})
+	require.NoError(t, err)
+	require.Equal(t, "func New() *Cart[string] {\n}\n", src)
+
+	_, err = Render(Constant{Name: "B"})
+	require.Error(t, err)
+	_, err = Render(Field{Name: "X"})
+	require.Error(t, err)
+	_, err = Render(Function{Name: "Broken", Params: []Param{{Name: "x", Type: "["}}})
+	require.Error(t, err)
+
+	point, err := ParseString("package test\n\ntype Point struct {\n\tX, Y int\n\tGrid [3][N]byte\n}\n")
+	require.NoError(t, err)
+	src, err = Render(point.Packages[0].Structs[0])
+	require.NoError(t, err)
+	require.Equal(t, "type Point struct {\n\tX, Y int\n\tGrid [3][N]byte\n}\n", src)
+
+	fragment, err := RenderFragment(true, h.Variable("Default"), h.Constant("Limit"))
+	require.NoError(t, err)
+	require.True(t, fragment.Overwrite)
+	decls, err := parseDeclarationsFromCodeFrament(fragment)
+	require.NoError(t, err)
+	require.Len(t, decls, 2)
+}
@@ -0,0 +1,304 @@
+package codesurgeon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ParseFS parses the packages of fsys under root recursively, like ParseModules does on disk, and
+// returns one ParsedInfo per directory holding Go files. Entity positions hold paths of fsys.
+// Packages are identified from the nearest go.mod file of fsys, and parsed outside of a module
+// when there's none. The paths of opts.Overlay are relative to the root of fsys.
+func ParseFS(fsys fs.FS, root string, opts ParseOptions) ([]*ParsedInfo, error) {
+	src := fsSource(fsys, opts.Overlay)
+	root = cleanFSPath(root)
+	if root == "" {
+		root = "."
+	}
+
+	var results []*ParsedInfo
+	err := src.walkDirs(root, func(p string) error {
+		if p != root && skipDir(path.Base(p)) {
+			return fs.SkipDir
+		}
+		mod, err := src.moduleRoot(p)
+		if err != nil {
+			return fmt.Errorf("error retrieving module path: %w", err)
+		}
+		info, err := parsePackageDir(src, packageDir{dir: p, mod: mod}, opts.fileFilter(p, src), opts.Cache, opts.Tolerant)
+		if err != nil {
+			return err
+		}
+		if len(info.Packages) > 0 || len(info.Diagnostics) > 0 {
+			results = append(results, info)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ComputeMethodSets(results...)
+	return results, nil
+}
+
+// sourceFS reads the files to parse, from disk or from a fs.FS, with an overlay of in-memory
+// contents replacing files or adding files to directories.
+type sourceFS struct {
+	fsys    fs.FS             // nil to read from disk
+	overlay map[string][]byte // Contents by cleaned path: absolute on disk, slash-separated in fsys
+}
+
+// diskSource returns the sourceFS reading from disk, with the paths of overlay made absolute.
+func diskSource(overlay map[string][]byte) sourceFS {
+	src := sourceFS{}
+	if len(overlay) > 0 {
+		src.overlay = make(map[string][]byte, len(overlay))
+		for name, content := range overlay {
+			if abs, err := filepath.Abs(name); err == nil {
+				name = abs
+			}
+			src.overlay[filepath.Clean(name)] = content
+		}
+	}
+	return src
+}
+
+// fsSource returns the sourceFS reading from fsys, with the paths of overlay relative to its root.
+func fsSource(fsys fs.FS, overlay map[string][]byte) sourceFS {
+	src := sourceFS{fsys: fsys}
+	if len(overlay) > 0 {
+		src.overlay = make(map[string][]byte, len(overlay))
+		for name, content := range overlay {
+			src.overlay[cleanFSPath(name)] = content
+		}
+	}
+	return src
+}
+
+// cleanFSPath returns name as a path valid in a fs.FS: slash-separated, cleaned and unrooted.
+func cleanFSPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+filepath.ToSlash(name)), "/")
+}
+
+func (s sourceFS) join(elem ...string) string {
+	if s.fsys != nil {
+		return path.Join(elem...)
+	}
+	return filepath.Join(elem...)
+}
+
+func (s sourceFS) base(name string) string {
+	if s.fsys != nil {
+		return path.Base(name)
+	}
+	return filepath.Base(name)
+}
+
+func (s sourceFS) stat(name string) (fs.FileInfo, error) {
+	if content, ok := s.overlay[name]; ok {
+		return overlayFileInfo{name: s.base(name), size: int64(len(content))}, nil
+	}
+	var info fs.FileInfo
+	var err error
+	if s.fsys != nil {
+		info, err = fs.Stat(s.fsys, name)
+	} else {
+		info, err = os.Stat(name)
+	}
+	if errors.Is(err, fs.ErrNotExist) && len(s.overlayChildren(name)) > 0 {
+		// A directory only holding files of the overlay
+		return overlayFileInfo{name: s.base(name), dir: true}, nil
+	}
+	return info, err
+}
+
+func (s sourceFS) readFile(name string) ([]byte, error) {
+	if content, ok := s.overlay[name]; ok {
+		return content, nil
+	}
+	if s.fsys != nil {
+		return fs.ReadFile(s.fsys, name)
+	}
+	return os.ReadFile(name)
+}
+
+// readDir returns the entries of the directory name sorted by name, with the files of the overlay
+// and the directories holding them. A directory missing from disk is read when the overlay holds files in it.
+func (s sourceFS) readDir(name string) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	var err error
+	if s.fsys != nil {
+		entries, err = fs.ReadDir(s.fsys, name)
+	} else {
+		entries, err = os.ReadDir(name)
+	}
+	children := s.overlayChildren(name)
+	if len(children) == 0 {
+		return entries, err
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	byName := make(map[string]fs.DirEntry, len(entries)+len(children))
+	for _, entry := range entries {
+		byName[entry.Name()] = entry
+	}
+	for child, isDir := range children {
+		if existing, ok := byName[child]; ok && isDir && existing.IsDir() {
+			continue
+		}
+		if isDir {
+			byName[child] = fs.FileInfoToDirEntry(overlayFileInfo{name: child, dir: true})
+		} else {
+			info, _ := s.stat(s.join(name, child))
+			byName[child] = fs.FileInfoToDirEntry(info)
+		}
+	}
+	merged := make([]fs.DirEntry, 0, len(byName))
+	for _, entry := range byName {
+		merged = append(merged, entry)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Name() < merged[j].Name()
+	})
+	return merged, nil
+}
+
+// overlayChildren returns the names of the files and directories of the overlay directly in dir,
+// reporting whether each one is a directory.
+func (s sourceFS) overlayChildren(dir string) map[string]bool {
+	if len(s.overlay) == 0 {
+		return nil
+	}
+	separator := string(filepath.Separator)
+	if s.fsys != nil {
+		separator = "/"
+	}
+	prefix := dir
+	if s.fsys != nil && dir == "." {
+		prefix = ""
+	} else if !strings.HasSuffix(prefix, separator) {
+		prefix += separator
+	}
+
+	children := make(map[string]bool)
+	for name := range s.overlay {
+		rest, ok := strings.CutPrefix(name, prefix)
+		if !ok || rest == "" {
+			continue
+		}
+		child, _, isDir := strings.Cut(rest, separator)
+		children[child] = children[child] || isDir
+	}
+	return children
+}
+
+// walkDirs calls fn for root and each directory under it, in lexical order, including the
+// directories only holding files of the overlay. When fn returns fs.SkipDir for a directory,
+// the directories under it are skipped.
+func (s sourceFS) walkDirs(root string, fn func(dir string) error) error {
+	if err := fn(root); err != nil {
+		if err == fs.SkipDir {
+			return nil
+		}
+		return err
+	}
+	entries, err := s.readDir(root)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if err := s.walkDirs(s.join(root, entry.Name()), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// goFiles returns the paths of the .go files of dir kept by filter, sorted by name, with the
+// files of the overlay.
+func (s sourceFS) goFiles(dir string, filter func(fs.FileInfo) bool) ([]string, error) {
+	infos := make(map[string]fs.FileInfo)
+	entries, err := s.readDir(dir)
+	if err != nil && !(errors.Is(err, fs.ErrNotExist) && len(s.overlay) > 0) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos[s.join(dir, entry.Name())] = info
+	}
+
+	names := make([]string, 0, len(infos))
+	for name, info := range infos {
+		if filter == nil || filter(info) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// openFile opens a file for build.Context.OpenFile.
+func (s sourceFS) openFile(name string) (io.ReadCloser, error) {
+	content, err := s.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// moduleRoot returns the module of dir, from the nearest go.mod file in dir or its parents.
+// In a fs.FS without go.mod, the packages are parsed outside of a module, like ParseString does.
+func (s sourceFS) moduleRoot(dir string) (*moduleRoot, error) {
+	if s.fsys == nil {
+		return getModulePath(dir)
+	}
+	for {
+		data, err := s.readFile(path.Join(dir, "go.mod"))
+		if err == nil {
+			return parseModuleRoot(data, dir)
+		}
+		if dir == "." {
+			return &moduleRoot{Dir: "."}, nil
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// overlayFileInfo describes a file of the overlay, or a directory only holding files of the overlay.
+type overlayFileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (fi overlayFileInfo) Name() string { return fi.name }
+func (fi overlayFileInfo) Size() int64  { return fi.size }
+func (fi overlayFileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+func (fi overlayFileInfo) ModTime() time.Time { return time.Time{} }
+func (fi overlayFileInfo) IsDir() bool        { return fi.dir }
+func (fi overlayFileInfo) Sys() any           { return nil }
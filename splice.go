@@ -0,0 +1,289 @@
+package codesurgeon
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+)
+
+// applyCodeFragment returns src with the operation of fragment applied.
+func applyCodeFragment(filename string, src []byte, fragment CodeFragment) ([]byte, error) {
+	switch fragment.Operation {
+	case "", OperationUpsert:
+		fset, fragmentFile, fragmentSrc, err := parseCodeFragment(fragment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse fragment: %w", err)
+		}
+		return spliceFragment(filename, src, fset, fragmentFile, fragmentSrc, fragment.Overwrite)
+	case OperationRemove:
+		return removeDeclarations(filename, src, fragment.Selectors)
+	}
+	return nil, fmt.Errorf("unknown fragment operation %q", fragment.Operation)
+}
+
+// spliceFragment upserts the declarations of a parsed fragment into src.
+func spliceFragment(filename string, src []byte, fset *token.FileSet, fragment *ast.File, fragmentSrc []byte, overwrite bool) ([]byte, error) {
+	for _, decl := range fragment.Decls {
+		var err error
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok != token.IMPORT {
+			src, err = spliceSpecs(filename, src, fset, fragment, fragmentSrc, gd, overwrite)
+		} else {
+			start, end := declRange(fset, fragment, decl)
+			src, err = spliceDeclaration(filename, src, decl, fragmentSrc[start:end], overwrite)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return src, nil
+}
+
+// spliceSpecs upserts the specs of a const, var or type declaration into src one by one: a spec
+// replaces the spec declaring the same names with overwrite, wherever it is, and a new spec is
+// added after the previous spec of newDecl when it's in a block. Other new specs are appended to
+// the end of the file, in a block when newDecl is one.
+func spliceSpecs(filename string, src []byte, fset *token.FileSet, fragment *ast.File, fragmentSrc []byte, newDecl *ast.GenDecl, overwrite bool) ([]byte, error) {
+	offset := func(pos token.Pos) int { return fset.Position(pos).Offset }
+	keyword := newDecl.Tok.String() + " "
+	blockDoc := ""
+	if newDecl.Lparen.IsValid() && newDecl.Doc != nil {
+		blockDoc = string(fragmentSrc[offset(newDecl.Doc.Pos()):offset(newDecl.Doc.End())]) + "\n"
+	}
+
+	previous := "" // Name of the previous spec of newDecl
+	for _, spec := range newDecl.Specs {
+		// The doc of a declaration that isn't a block is the doc of its spec
+		var doc, body string
+		if newDecl.Lparen.IsValid() {
+			start, end := specRange(fset, spec)
+			body = indentLines(string(fragmentSrc[start:end]), "\t", "")
+		} else {
+			start, end := declRange(fset, fragment, newDecl)
+			doc, body = string(fragmentSrc[start:offset(newDecl.TokPos)]), string(fragmentSrc[offset(spec.Pos()):end])
+		}
+		names := specNames(spec)
+
+		fileFset := token.NewFileSet()
+		file, err := parser.ParseFile(fileFset, filename, src, parser.ParseComments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse file: %w", err)
+		}
+
+		if existing, i := findSpec(file, newDecl.Tok, names); existing != nil {
+			previous = names[0]
+			if !overwrite {
+				continue
+			}
+			if err := checkSpecReplacement(existing.Specs[i], spec); err != nil {
+				return nil, err
+			}
+			if existing.Lparen.IsValid() {
+				// The indentation of the first line is before the replaced spec
+				start, end := specRange(fileFset, existing.Specs[i])
+				src = splice(src, start, end, strings.TrimPrefix(indentLines(doc+body, "", "\t"), "\t"))
+			} else {
+				start, end := declRange(fileFset, file, existing)
+				src = splice(src, start, end, doc+keyword+body)
+			}
+			continue
+		}
+
+		if previous != "" {
+			if block, i := findSpec(file, newDecl.Tok, []string{previous}); block != nil && block.Lparen.IsValid() {
+				_, end := specRange(fileFset, block.Specs[i])
+				src = splice(src, end, end, "\n"+indentLines(doc+body, "", "\t"))
+				previous = names[0]
+				continue
+			}
+		}
+		text := doc + keyword + body
+		if newDecl.Lparen.IsValid() {
+			text = blockDoc + keyword + "(\n" + indentLines(doc+body, "", "\t") + "\n)"
+		}
+		src = appendSource(src, text)
+		previous = names[0]
+	}
+	return src, nil
+}
+
+// parseCodeFragment parses the content of a fragment, adding a package clause when it has none.
+func parseCodeFragment(f CodeFragment) (*token.FileSet, *ast.File, []byte, error) {
+	code := strings.TrimSpace(f.Content)
+	// check if no package is defined
+	if !strings.HasPrefix(code, "package") {
+		code = "package main\n\n" + code
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", code, parser.AllErrors|parser.ParseComments)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return fset, file, []byte(code), nil
+}
+
+// spliceDeclaration upserts newDecl, a function, a method or imports, written as text, into src:
+// it replaces the function of the same name and receiver when overwrite is set and appends it to
+// the end of the file when there's none. Imports are merged into the imports of the file instead.
+func spliceDeclaration(filename string, src []byte, newDecl ast.Decl, text []byte, overwrite bool) ([]byte, error) {
+	// src changes with every declaration, so it's parsed again to get the positions right
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filename, src, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file: %w", err)
+	}
+
+	if gd, ok := newDecl.(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+		return spliceImports(fset, file, src, gd), nil
+	}
+
+	i := findDeclaration(file, newDecl)
+	if i < 0 {
+		return appendSource(src, string(text)), nil
+	}
+	if !overwrite {
+		return src, nil
+	}
+	start, end := declRange(fset, file, file.Decls[i])
+	return splice(src, start, end, string(text)), nil
+}
+
+// findDeclaration returns the index in file.Decls of the function or method newDecl would
+// replace, the one of the same name and receiver. It returns -1 when there's none.
+func findDeclaration(file *ast.File, newDecl ast.Decl) int {
+	newFunc, ok := newDecl.(*ast.FuncDecl)
+	if !ok {
+		return -1
+	}
+	for i, decl := range file.Decls {
+		existing, ok := decl.(*ast.FuncDecl)
+		if ok && existing.Name.Name == newFunc.Name.Name && getReceiverType(existing) == getReceiverType(newFunc) {
+			return i
+		}
+	}
+	return -1
+}
+
+// spliceImports adds the imports of newImports that file doesn't have to its first import
+// declaration, or to a new one after the package clause when it has none.
+func spliceImports(fset *token.FileSet, file *ast.File, src []byte, newImports *ast.GenDecl) []byte {
+	var specs []string
+	for _, spec := range newImports.Specs {
+		imp := spec.(*ast.ImportSpec)
+		if hasImport(file, imp) {
+			continue
+		}
+		if imp.Name != nil {
+			specs = append(specs, imp.Name.Name+" "+imp.Path.Value)
+		} else {
+			specs = append(specs, imp.Path.Value)
+		}
+	}
+	if len(specs) == 0 {
+		return src
+	}
+
+	offset := fset.Position(file.Name.End()).Offset
+	for _, decl := range file.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.IMPORT {
+			continue
+		}
+		offset = fset.Position(gd.End()).Offset
+		if len(gd.Specs) == 1 && gd.Specs[0].(*ast.ImportSpec).Path.Value == `"C"` {
+			// The comment of import "C" is the preamble of cgo, it has to stay on its own
+			continue
+		}
+		if gd.Lparen.IsValid() {
+			rparen := fset.Position(gd.Rparen).Offset
+			text := "\t" + strings.Join(specs, "\n\t") + "\n"
+			if line := bytes.TrimRight(src[:rparen], " \t"); len(line) > 0 && line[len(line)-1] != '\n' {
+				text = "\n" + text
+			}
+			return splice(src, rparen, rparen, text)
+		}
+		start, end := fset.Position(gd.Specs[0].Pos()).Offset, fset.Position(gd.Specs[0].End()).Offset
+		return splice(src, start, end, "(\n\t"+string(src[start:end])+"\n\t"+strings.Join(specs, "\n\t")+"\n)")
+	}
+	return splice(src, offset, offset, "\n\nimport (\n\t"+strings.Join(specs, "\n\t")+"\n)")
+}
+
+// hasImport reports whether file already imports the path of imp under the same name.
+func hasImport(file *ast.File, imp *ast.ImportSpec) bool {
+	name := func(s *ast.ImportSpec) string {
+		if s.Name != nil {
+			return s.Name.Name
+		}
+		return ""
+	}
+	for _, existing := range file.Imports {
+		if existing.Path.Value == imp.Path.Value && name(existing) == name(imp) {
+			return true
+		}
+	}
+	return false
+}
+
+// declRange returns the offsets of decl in the source of file, from its doc comment to the
+// comment on the line it ends, if any.
+func declRange(fset *token.FileSet, file *ast.File, decl ast.Decl) (int, int) {
+	start, end := decl.Pos(), decl.End()
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	case *ast.GenDecl:
+		if d.Doc != nil {
+			start = d.Doc.Pos()
+		}
+	}
+	line := fset.Position(end).Line
+	for _, cg := range file.Comments {
+		if cg.Pos() >= end {
+			if fset.Position(cg.Pos()).Line == line {
+				end = cg.End()
+			}
+			break
+		}
+	}
+	return fset.Position(start).Offset, fset.Position(end).Offset
+}
+
+// appendSource returns src with the declaration text appended, after a blank line.
+func appendSource(src []byte, text string) []byte {
+	var buf bytes.Buffer
+	buf.Write(bytes.TrimRight(src, "\n"))
+	buf.WriteString("\n\n")
+	buf.WriteString(text)
+	buf.WriteString("\n")
+	return buf.Bytes()
+}
+
+// indentLines returns text with trim removed from the start of the lines following the first one,
+// then prefix added to every line that isn't empty. The specs of a block are indented once more
+// than the specs declared on their own.
+func indentLines(text string, trim, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if i > 0 {
+			line = strings.TrimPrefix(line, trim)
+		}
+		if line != "" {
+			line = prefix + line
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// splice returns src with the bytes from start to end replaced by text.
+func splice(src []byte, start, end int, text string) []byte {
+	out := make([]byte, 0, len(src)-(end-start)+len(text))
+	out = append(out, src[:start]...)
+	out = append(out, text...)
+	return append(out, src[end:]...)
+}
@@ -6,13 +6,17 @@ import (
 	"errors"
 	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/doc"
 	"go/parser"
 	"go/printer"
 	"go/token"
+	"go/types"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 
 	"golang.org/x/mod/modfile"
@@ -26,10 +30,12 @@ var FS embed.FS
 
 // ParsedInfo holds parsed information about Go packages.
 type ParsedInfo struct {
-	Modules   []Module  `json:"modules"`
-	Packages  []Package `json:"packages"`  // Deprecated: use Modules instead
-	Directory string    `json:"directory"` // if information was parsed from a directory. It's either a directory or a file
-	File      string    `json:"file"`      // if information was parsed from a single file. It's either a directory or a file
+	Modules     []Module       `json:"modules"`
+	Packages    []Package      `json:"packages"`              // Deprecated: use Modules instead
+	Directory   string         `json:"directory"`             // if information was parsed from a directory. It's either a directory or a file
+	File        string         `json:"file"`                  // if information was parsed from a single file. It's either a directory or a file
+	Errors      []PackageError `json:"errors,omitempty"`      // Packages that failed to parse, only set by ParseModules
+	Diagnostics []Diagnostic   `json:"diagnostics,omitempty"` // Problems found in the files parsed in tolerant mode
 }
 
 // Module represents a Go module with its packages.
@@ -42,16 +48,49 @@ type Module struct {
 
 // Package represents a Go package with its components such as imports, structs, functions, etc.
 type Package struct {
-	Package    string      `json:"package"`     // Name of the package as seen in the package declaration (e.g., "main")
-	ModuleName string      `json:"module_name"` // Name of the module as seen in the go.mod file
-	Imports    []Import    `json:"imports,omitemity"`
-	Structs    []Struct    `json:"structs,omitemity"`
-	Functions  []Function  `json:"functions,omitemity"`
-	Variables  []Variable  `json:"variables,omitemity"`
-	Constants  []Constant  `json:"constants,omitemity"`
-	Interfaces []Interface `json:"interfaces,omitemity"`
+	Package    string       `json:"package"`               // Name of the package as seen in the package declaration (e.g., "main")
+	ModuleName string       `json:"module_name"`           // Name of the module as seen in the go.mod file
+	ImportPath string       `json:"import_path,omitempty"` // Import path of the package (e.g., "github.com/x/y/pkg"), its name when parsed outside of a module
+	Docs       []string     `json:"docs,omitempty"`        // Package doc comment
+	Imports    []Import     `json:"imports,omitemity"`
+	Structs    []Struct     `json:"structs,omitemity"`
+	Functions  []Function   `json:"functions,omitemity"`
+	Variables  []Variable   `json:"variables,omitemity"`
+	Constants  []Constant   `json:"constants,omitemity"`
+	Interfaces []Interface  `json:"interfaces,omitemity"`
+	Types      []TypeDecl   `json:"types,omitemity"`   // Named types that are neither structs nor interfaces, including aliases
+	Enums      []Enum       `json:"enums,omitemity"`   // Const blocks typed with a named type, e.g. iota enumerations
+	Files      []SourceFile `json:"files,omitempty"`   // Files the package was parsed from, matching the File of the entity positions
+	IsTest     bool         `json:"is_test,omitempty"` // Every file of the package is a _test.go file, e.g. an external foo_test package
 
 	PtrModule *Module `json:"-"` // Pointer to the module that this package belongs to
+
+	typed *typedPackage // Type information, only set by ParseModule
+}
+
+// Synopsis returns the first sentence of the package doc comment.
+func (p Package) Synopsis() string {
+	return new(doc.Package).Synopsis(strings.Join(p.Docs, "\n"))
+}
+
+// File returns the SourceFile named name, e.g. the File of the Position of an entity.
+func (p Package) File(name string) (SourceFile, bool) {
+	for _, f := range p.Files {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return SourceFile{}, false
+}
+
+// SourceFile is a file a package was parsed from.
+type SourceFile struct {
+	Name            string      `json:"name"`
+	IsTest          bool        `json:"is_test,omitempty"`          // The file is a _test.go file
+	BuildConstraint string      `json:"build_constraint,omitempty"` // Expression of the //go:build line (or of the legacy // +build lines), e.g. "linux && !cgo"
+	Directives      []Directive `json:"directives,omitempty"`       // Every directive of the file, e.g. //go:build, //go:generate or //go:embed
+	Header          string      `json:"header,omitempty"`           // Comments before the package clause other than the package doc, e.g. a license header
+	Imports         []Import    `json:"imports,omitempty"`          // Imports of the file, with their alias
 }
 
 type Import struct {
@@ -63,48 +102,118 @@ type Import struct {
 
 // Interface represents a Go interface and its methods.
 type Interface struct {
-	Name       string   `json:"name"`
-	Methods    []Method `json:"methods,omitemity"`
-	Docs       []string `json:"docs,omitemity"`
-	Definition string   `json:"definition,omitempty"` // Full Go code definition of the interface
+	Name              string           `json:"name"`
+	TypeParams        []TypeParam      `json:"type_params,omitempty"` // Type parameters of a generic interface
+	Methods           []Method         `json:"methods,omitemity"`
+	Unions            [][]TypeTerm     `json:"unions,omitempty"`       // Union elements of a constraint interface, one entry per line (e.g. ~int | ~string)
+	Embeds            []string         `json:"embeds,omitempty"`       // Embedded interfaces as written in the source (e.g. "io.Reader")
+	MethodSet         []MethodSetEntry `json:"method_set,omitempty"`   // Methods declared on the interface and on the embedded interfaces found in the parsed module
+	Implementers      []Implementation `json:"implementers,omitempty"` // Structs of the parsed module satisfying the interface
+	Docs              []string         `json:"docs,omitemity"`
+	Directives        []Directive      `json:"directives,omitempty"`
+	Deprecated        bool             `json:"deprecated,omitempty"`
+	DeprecatedMessage string           `json:"deprecated_message,omitempty"`
+	Definition        string           `json:"definition,omitempty"` // Full Go code definition of the interface
+	Position          Position         `json:"position"`
 
 	PtrPackage *Package `json:"-"` // Pointer to the package that this interface belongs to
 }
 
 // Struct represents a Go struct and its fields and methods.
 type Struct struct {
-	Name       string   `json:"name"`
-	Fields     []Field  `json:"fields,omitemity"`
-	Methods    []Method `json:"methods,omitemity"`
-	Docs       []string `json:"docs,omitemity"`
-	Definition string   `json:"definition,omitempty"` // Full Go code definition of the struct
+	Name              string           `json:"name"`
+	TypeParams        []TypeParam      `json:"type_params,omitempty"` // Type parameters of a generic struct
+	Fields            []Field          `json:"fields,omitemity"`
+	Methods           []Method         `json:"methods,omitemity"`
+	MethodSet         []MethodSetEntry `json:"method_set,omitempty"` // Methods declared on the struct and promoted from embedded fields found in the parsed module
+	Implements        []Implementation `json:"implements,omitempty"` // Interfaces of the parsed module satisfied by the struct
+	Docs              []string         `json:"docs,omitemity"`
+	Directives        []Directive      `json:"directives,omitempty"`
+	Deprecated        bool             `json:"deprecated,omitempty"`
+	DeprecatedMessage string           `json:"deprecated_message,omitempty"`
+	Definition        string           `json:"definition,omitempty"` // Full Go code definition of the struct
+	Position          Position         `json:"position"`
 
 	PtrPackage *Package `json:"-"` // Pointer to the package that this struct belongs to
 }
 
+// TypeDecl represents a named type that is neither a struct nor an interface,
+// such as `type Status string`, `type HandlerFunc func()` or the alias `type X = pkg.Y`.
+type TypeDecl struct {
+	Name              string      `json:"name"`
+	TypeParams        []TypeParam `json:"type_params,omitempty"` // Type parameters of a generic type
+	Underlying        string      `json:"underlying"`            // Underlying (or aliased) type as written in the source
+	TypeDetails       TypeDetails `json:"type_details"`
+	IsAlias           bool        `json:"is_alias"`
+	Methods           []Method    `json:"methods,omitemity"`
+	Docs              []string    `json:"docs,omitemity"`
+	Directives        []Directive `json:"directives,omitempty"`
+	Deprecated        bool        `json:"deprecated,omitempty"`
+	DeprecatedMessage string      `json:"deprecated_message,omitempty"`
+	Definition        string      `json:"definition,omitempty"` // Full Go code definition of the type
+	Position          Position    `json:"position"`
+
+	PtrPackage *Package `json:"-"` // Pointer to the package that this type belongs to
+}
+
 // Method represents a method in a Go struct or interface.
 type Method struct {
-	Receiver   string   `json:"receiver,omitempty"` // Receiver type (e.g., "*MyStruct" or "MyStruct")
-	Name       string   `json:"name"`
-	Params     []Param  `json:"params,omitemity"`
-	Returns    []Param  `json:"returns,omitemity"`
-	Docs       []string `json:"docs,omitemity"`
-	Signature  string   `json:"signature"`
-	Body       string   `json:"body,omitempty"`       // New field for method body
-	Definition string   `json:"definition,omitempty"` // Full Go code definition of the method
+	Receiver          string      `json:"receiver,omitempty"`      // Receiver type (e.g., "*MyStruct" or "MyStruct")
+	ReceiverName      string      `json:"receiver_name,omitempty"` // Name of the receiver (e.g., "s"), empty when unnamed
+	TypeParams        []TypeParam `json:"type_params,omitempty"`   // Type parameters of a generic receiver, e.g. K in (s *Set[K])
+	Name              string      `json:"name"`
+	Params            []Param     `json:"params,omitemity"`
+	Returns           []Param     `json:"returns,omitemity"`
+	Docs              []string    `json:"docs,omitemity"`
+	Directives        []Directive `json:"directives,omitempty"`
+	Deprecated        bool        `json:"deprecated,omitempty"`
+	DeprecatedMessage string      `json:"deprecated_message,omitempty"`
+	Signature         string      `json:"signature"`
+	Body              string      `json:"body,omitempty"`       // New field for method body
+	Definition        string      `json:"definition,omitempty"` // Full Go code definition of the method
+	Position          Position    `json:"position"`
+	Calls             []CallRef   `json:"calls,omitempty"` // Functions and methods called from the body
 
 	PtrStruct *Struct `json:"-"` // Pointer to the struct that this method belongs to
 }
 
+// ReceiverTypeName returns the name of the receiver type without pointer or type arguments,
+// e.g. "Set" for a method declared on "*Set[K]".
+func (m Method) ReceiverTypeName() string {
+	name := strings.TrimPrefix(m.Receiver, "*")
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
 // Function represents a Go function with its parameters, return types, and documentation.
 type Function struct {
-	Name       string   `json:"name"`
-	Params     []Param  `json:"params,omitemity"`
-	Returns    []Param  `json:"returns,omitemity"`
-	Docs       []string `json:"docs,omitemity"`
-	Signature  string   `json:"signature"`
-	Body       string   `json:"body,omitempty"`       // New field for function body
-	Definition string   `json:"definition,omitempty"` // Full Go code definition of the function
+	Name              string      `json:"name"`
+	TypeParams        []TypeParam `json:"type_params,omitempty"` // Type parameters of a generic function
+	Params            []Param     `json:"params,omitemity"`
+	Returns           []Param     `json:"returns,omitemity"`
+	Docs              []string    `json:"docs,omitemity"`
+	Directives        []Directive `json:"directives,omitempty"`
+	Deprecated        bool        `json:"deprecated,omitempty"`
+	DeprecatedMessage string      `json:"deprecated_message,omitempty"`
+	Signature         string      `json:"signature"`
+	Body              string      `json:"body,omitempty"`       // New field for function body
+	Definition        string      `json:"definition,omitempty"` // Full Go code definition of the function
+	Position          Position    `json:"position"`
+	Calls             []CallRef   `json:"calls,omitempty"` // Functions and methods called from the body
+}
+
+// TypeParam represents a type parameter of a generic function, method or type.
+type TypeParam struct {
+	Name       string `json:"name"`
+	Constraint string `json:"constraint,omitempty"` // Constraint as written in the source (e.g. "any", "comparable", "~int | ~string")
+}
+
+// TypeTerm represents a single term of a union element in a constraint interface.
+type TypeTerm struct {
+	Type  string `json:"type"`
+	Tilde bool   `json:"tilde,omitempty"` // True for approximation terms like ~int
 }
 
 // Param represents a parameter or return value in a Go function or method.
@@ -124,15 +233,22 @@ type Param struct {
 
 // Field represents a field in a Go struct.
 type Field struct {
-	Name        string      `json:"name"`
-	Type        string      `json:"type"`
-	TypeDetails TypeDetails `json:"type_details"`
-	Tag         string      `json:"tag"`
-	Private     bool        `json:"private"`
-	Pointer     bool        `json:"pointer"`
-	Slice       bool        `json:"slice"`
-	Docs        []string    `json:"docs,omitemity"`
-	Comment     string      `json:"comment,omitempty"`
+	Name              string      `json:"name"`
+	Names             []string    `json:"names,omitempty"` // Every name of a field declaring several, e.g. "A, B int". Name is the first one
+	Type              string      `json:"type"`
+	TypeDetails       TypeDetails `json:"type_details"`
+	Tag               string      `json:"tag"`            // Raw tag, without its quotes
+	Tags              []Tag       `json:"tags,omitempty"` // Keys of the tag, in the order they're written
+	Private           bool        `json:"private"`
+	Embedded          bool        `json:"embedded"` // Embedded (anonymous) field, Name is then the name of its type
+	Pointer           bool        `json:"pointer"`
+	Slice             bool        `json:"slice"` // Slice or array
+	Docs              []string    `json:"docs,omitemity"`
+	Comment           string      `json:"comment,omitempty"`
+	Directives        []Directive `json:"directives,omitempty"`
+	Deprecated        bool        `json:"deprecated,omitempty"`
+	DeprecatedMessage string      `json:"deprecated_message,omitempty"`
+	Position          Position    `json:"position"`
 
 	PtrStruct *Struct `json:"-"` // Pointer to the struct that this field belongs to
 }
@@ -146,9 +262,23 @@ type TypeDetails struct {
 	IsPointer      bool
 	IsSlice        bool
 	IsMap          bool
-	IsBuiltin      bool // if string, int, etc
-	IsExternal     bool // if the type is from another package
+	IsBuiltin      bool   // if string, int, etc
+	IsExternal     bool   // if the type is from another package
+	Kind           string // kind of the underlying type, like "struct", "map" or "basic". Only set by ParseModule
 	TypeReferences []TypeReference
+
+	// Structure of composite types, to traverse them instead of parsing TypeName
+	Elem     *TypeDetails `json:",omitempty"` // Element of pointers, slices, arrays, channels and variadic parameters, value of maps
+	Key      *TypeDetails `json:",omitempty"` // Key of maps
+	IsArray  bool         `json:",omitempty"`
+	ArrayLen string       `json:",omitempty"` // Length of arrays as written, e.g. "3" or "N"
+	IsChan   bool         `json:",omitempty"`
+	ChanDir  string       `json:",omitempty"` // Direction of channels: "both", "send" or "recv"
+	IsFunc   bool         `json:",omitempty"`
+	Params   []Param      `json:",omitempty"` // Parameters of func types
+	Results  []Param      `json:",omitempty"` // Results of func types
+	IsStruct bool         `json:",omitempty"`
+	Fields   []Field      `json:",omitempty"` // Fields of inline struct types
 }
 
 type TypeReference struct {
@@ -159,16 +289,91 @@ type TypeReference struct {
 
 // Variable represents a global variable in a Go package.
 type Variable struct {
-	Name string   `json:"name"`
-	Type string   `json:"type"`
-	Docs []string `json:"docs,omitemity"`
+	Name              string      `json:"name"`
+	Type              string      `json:"type"`
+	Value             string      `json:"value,omitempty"` // Initial value as written in the source
+	Docs              []string    `json:"docs,omitemity"`
+	Directives        []Directive `json:"directives,omitempty"`
+	Deprecated        bool        `json:"deprecated,omitempty"`
+	DeprecatedMessage string      `json:"deprecated_message,omitempty"`
+	Position          Position    `json:"position"`
 }
 
 // Constant represents a constant in a Go package.
 type Constant struct {
-	Name  string   `json:"name"`
-	Value string   `json:"value"`
-	Docs  []string `json:"docs,omitemity"`
+	Name              string      `json:"name"`
+	Type              string      `json:"type,omitempty"` // Type as written in the source, empty for untyped constants
+	Value             string      `json:"value"`
+	Docs              []string    `json:"docs,omitemity"`
+	Directives        []Directive `json:"directives,omitempty"`
+	Deprecated        bool        `json:"deprecated,omitempty"`
+	DeprecatedMessage string      `json:"deprecated_message,omitempty"`
+	Position          Position    `json:"position"`
+}
+
+// Enum represents the constants of a const block that share a named type,
+// such as `const ( StatusA Status = iota; StatusB )`.
+type Enum struct {
+	Type     string       `json:"type"` // Name of the type the members belong to (e.g. "Status")
+	Members  []EnumMember `json:"members"`
+	Docs     []string     `json:"docs,omitemity"`
+	Position Position     `json:"position"` // Position of the const block
+}
+
+// EnumMember represents a single constant of an Enum, in declaration order.
+type EnumMember struct {
+	Name       string   `json:"name"`
+	Value      string   `json:"value"`      // Resolved value (e.g. "2" or "\"pending\""), empty if it couldn't be evaluated
+	Expression string   `json:"expression"` // Expression as written or implicitly repeated (e.g. "iota + 1")
+	Docs       []string `json:"docs,omitemity"`
+	Position   Position `json:"position"`
+}
+
+// Position describes where a parsed entity is located in its source file.
+type Position struct {
+	File      string `json:"file,omitempty"` // Path of the file, empty when parsed from a string
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	Offset    int    `json:"offset"` // Byte offset of the start of the entity
+	EndLine   int    `json:"end_line"`
+	EndColumn int    `json:"end_column"`
+	EndOffset int    `json:"end_offset"` // Byte offset just after the end of the entity
+}
+
+// String returns the position formatted as file:line:column.
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+// IsValid reports whether the position has been set.
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// newPosition returns the Position spanned by node.
+func newPosition(fset *token.FileSet, node ast.Node) Position {
+	return newPositionRange(fset, node.Pos(), node.End())
+}
+
+// newPositionRange returns the Position spanned by the [pos, end) range.
+func newPositionRange(fset *token.FileSet, pos, end token.Pos) Position {
+	if fset == nil || !pos.IsValid() {
+		return Position{}
+	}
+	start := fset.Position(pos)
+	stop := fset.Position(end)
+	return Position{
+		File:      start.Filename,
+		Line:      start.Line,
+		Column:    start.Column,
+		Offset:    start.Offset,
+		EndLine:   stop.Line,
+		EndColumn: stop.Column,
+		EndOffset: stop.Offset,
+	}
 }
 
 // ParseFile parses a Go file or directory and returns the parsed information.
@@ -184,7 +389,7 @@ func ParseDirectory(fileOrDirectory string) (*ParsedInfo, error) {
 // ParseString parses Go source code provided as a string and returns the parsed information.
 func ParseString(fileContent string) (*ParsedInfo, error) {
 	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, "", fileContent, parser.ParseComments|parser.AllErrors|parser.DeclarationErrors)
+	file, err := parser.ParseFile(fset, "", fileContent, parseMode)
 	if err != nil {
 		return nil, err
 	}
@@ -196,7 +401,7 @@ func ParseString(fileContent string) (*ParsedInfo, error) {
 		},
 	}
 
-	return extractParsedInfo(packages, "", "")
+	return extractParsedInfo(fset, packages, "", "", nil)
 }
 
 func augment(m map[string]interface{}, n map[string]interface{}) map[string]interface{} {
@@ -212,6 +417,33 @@ func augment(m map[string]interface{}, n map[string]interface{}) map[string]inte
 
 // ParseDirectoryRecursive parses a directory recursively and returns the parsed information.
 func ParseDirectoryRecursive(path string) ([]*ParsedInfo, error) {
+	return ParseDirectoryRecursiveWithOptions(path, ParseOptions{AllFiles: true})
+}
+
+// ParseDirectoryWithOptions parses a directory like ParseDirectory, keeping only the files
+// go build would compile with the GOOS, GOARCH, BuildTags and Tests of opts.
+// Directories are parsed through opts.Cache when it's set. With opts.Tolerant, syntax errors
+// are reported in the Diagnostics of the result instead of failing the parsing.
+// The files of opts.Overlay replace the ones on disk.
+func ParseDirectoryWithOptions(path string, opts ParseOptions) (*ParsedInfo, error) {
+	src := diskSource(opts.Overlay)
+	if fi, err := os.Stat(path); opts.Cache != nil && err == nil && fi.IsDir() {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		mod, err := getModulePath(abs)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving module path: %w", err)
+		}
+		return opts.Cache.parseDir(src, abs, opts.fileFilter(abs, src), mod, opts.Tolerant)
+	}
+	return parseDirectoryWithFilter(src, path, opts.fileFilter(path, src), opts.Tolerant)
+}
+
+// ParseDirectoryRecursiveWithOptions parses a directory recursively like ParseDirectoryRecursive,
+// parsing each directory with ParseDirectoryWithOptions.
+func ParseDirectoryRecursiveWithOptions(path string, opts ParseOptions) ([]*ParsedInfo, error) {
 	var results []*ParsedInfo
 
 	err := filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
@@ -222,9 +454,7 @@ func ParseDirectoryRecursive(path string) ([]*ParsedInfo, error) {
 			if strings.Contains(p, ".git") {
 				return nil
 			}
-			parsed, err := ParseDirectoryWithFilter(p, func(info fs.FileInfo) bool {
-				return true
-			}) // Assuming this function parses a single file
+			parsed, err := ParseDirectoryWithOptions(p, opts)
 			if err != nil {
 				return err
 			}
@@ -232,32 +462,26 @@ func ParseDirectoryRecursive(path string) ([]*ParsedInfo, error) {
 		}
 		return nil
 	})
-	return results, err
+	if err != nil {
+		return results, err
+	}
+	// Resolve embedded types across all the packages that were parsed
+	ComputeMethodSets(results...)
+	return results, nil
+}
+
+// moduleRoot is a module found from a go.mod file.
+type moduleRoot struct {
+	Path string // Module path declared in go.mod
+	Dir  string // Directory containing go.mod
 }
 
 // getModulePath reads the module name from the go.mod file.
-func getModulePath(path string) (*struct {
-	Path string
-	Dir  string
-}, error) {
+func getModulePath(path string) (*moduleRoot, error) {
 	dir := path
 	for {
 		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
-			data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
-			if err != nil {
-				return nil, fmt.Errorf("error reading go.mod: %w", err)
-			}
-			modFile, err := modfile.Parse("go.mod", data, nil)
-			if err != nil {
-				return nil, fmt.Errorf("error parsing go.mod: %w", err)
-			}
-			return &struct {
-				Path string
-				Dir  string
-			}{
-				Path: modFile.Module.Mod.Path,
-				Dir:  dir,
-			}, nil
+			return readModuleRoot(dir)
 		}
 		parent := filepath.Dir(dir)
 		if parent == dir {
@@ -267,34 +491,72 @@ func getModulePath(path string) (*struct {
 	}
 }
 
+// readModuleRoot reads the go.mod file of dir.
+func readModuleRoot(dir string) (*moduleRoot, error) {
+	data, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("error reading go.mod: %w", err)
+	}
+	return parseModuleRoot(data, dir)
+}
+
+// parseModuleRoot parses the content of the go.mod file of dir.
+func parseModuleRoot(data []byte, dir string) (*moduleRoot, error) {
+	modFile, err := modfile.Parse("go.mod", data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing go.mod: %w", err)
+	}
+	return &moduleRoot{
+		Path: modFile.Module.Mod.Path,
+		Dir:  dir,
+	}, nil
+}
+
 // ParseDirectoryWithFilter parses a directory with an optional filter function to include specific files.
 func ParseDirectoryWithFilter(fileOrDirectory string, filter func(fs.FileInfo) bool) (*ParsedInfo, error) {
-	fi, err := os.Stat(fileOrDirectory)
+	return parseDirectoryWithFilter(sourceFS{}, fileOrDirectory, filter, false)
+}
+
+// parseDirectoryWithFilter is ParseDirectoryWithFilter reading the files from src, keeping the declarations
+// of the files with syntax errors and reporting the errors as diagnostics when tolerant is set.
+func parseDirectoryWithFilter(src sourceFS, fileOrDirectory string, filter func(fs.FileInfo) bool, tolerant bool) (*ParsedInfo, error) {
+	// Parse using the absolute path so that positions point to the real files
+	if abs, err := filepath.Abs(fileOrDirectory); err == nil {
+		fileOrDirectory = abs
+	}
+	fi, err := src.stat(fileOrDirectory)
 	if err != nil {
 		return nil, err
 	}
 
 	var packages map[string]*ast.Package
+	var diagnostics []Diagnostic
 	fset := token.NewFileSet()
 
 	isDir := true
 	switch mode := fi.Mode(); {
 	case mode.IsDir():
-		packages, err = parser.ParseDir(fset, fileOrDirectory, filter, parser.ParseComments|parser.AllErrors|parser.DeclarationErrors)
+		packages, diagnostics, err = parseGoDir(fset, src, fileOrDirectory, filter, tolerant)
 		if err != nil {
 			return nil, err
 		}
 	case mode.IsRegular():
 		isDir = false
-		file, err := parser.ParseFile(fset, fileOrDirectory, nil, parser.ParseComments|parser.AllErrors|parser.DeclarationErrors)
+		content, err := src.readFile(fileOrDirectory)
 		if err != nil {
 			return nil, err
 		}
-		packages = map[string]*ast.Package{
-			fileOrDirectory: {
+		file, fileDiagnostics, err := parseGoFile(fset, fileOrDirectory, content, tolerant)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = fileDiagnostics
+		packages = map[string]*ast.Package{}
+		if file.Name.Name != "" {
+			packages[fileOrDirectory] = &ast.Package{
 				Name:  file.Name.Name,
 				Files: map[string]*ast.File{fileOrDirectory: file},
-			},
+			}
 		}
 	}
 
@@ -303,31 +565,31 @@ func ParseDirectoryWithFilter(fileOrDirectory string, filter func(fs.FileInfo) b
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving module path: %w", err)
 	}
-	relPath, err := filepath.Rel(modulePath.Dir, fileOrDirectory)
-	if err != nil {
-		return nil, fmt.Errorf("error resolving relative path: %w", err)
-	}
 
-	parsedInfo, err := extractParsedInfo(packages, modulePath.Path, relPath)
+	parsedInfo, err := extractModuleParsedInfo(fset, packages, modulePath, fileOrDirectory)
 	if err != nil {
 		return nil, err
 	}
+	parsedInfo.Diagnostics = diagnostics
 	if isDir {
 		parsedInfo.Directory = fileOrDirectory
-		if abs, err := filepath.Abs(fileOrDirectory); err == nil {
-			parsedInfo.Directory = abs
-		}
 	} else {
 		parsedInfo.File = fileOrDirectory
-		if abs, err := filepath.Abs(fileOrDirectory); err == nil {
-			parsedInfo.File = abs
-		}
 	}
 	return parsedInfo, nil
 }
 
+// extractModuleParsedInfo extracts the parsed information of the packages found in path, a directory or file of mod.
+func extractModuleParsedInfo(fset *token.FileSet, packages map[string]*ast.Package, mod *moduleRoot, path string) (*ParsedInfo, error) {
+	relPath, err := filepath.Rel(mod.Dir, path)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving relative path: %w", err)
+	}
+	return extractParsedInfo(fset, packages, mod.Path, relPath, nil)
+}
+
 // extractStructs extracts structs from the provided documentation package.
-func extractStructs(docPkg *doc.Package, ourPkg Package) ([]Struct, error) {
+func extractStructs(fset *token.FileSet, docPkg *doc.Package, ourPkg Package) ([]Struct, error) {
 	var structs []Struct
 	for _, t := range docPkg.Types {
 		if t == nil || t.Decl == nil {
@@ -342,19 +604,24 @@ func extractStructs(docPkg *doc.Package, ourPkg Package) ([]Struct, error) {
 
 			structType, ok := typeSpec.Type.(*ast.StructType)
 			if ok {
+				doc := specDoc(typeSpec.Doc, t.Decl)
 				parsedStruct := Struct{
-					Name:    t.Name,
-					Fields:  make([]Field, 0, len(structType.Fields.List)),
-					Docs:    getDocsForStruct(t.Doc),
-					Methods: make([]Method, 0),
+					Name:       t.Name,
+					TypeParams: extractTypeParams(typeSpec.TypeParams),
+					Fields:     make([]Field, 0, len(structType.Fields.List)),
+					Docs:       getDocsForStruct(docComment(doc).Text()),
+					Directives: extractDirectives(fset, doc, typeSpec.Comment),
+					Methods:    make([]Method, 0),
+					Position:   newPosition(fset, typeSpec),
 
 					PtrPackage: &ourPkg,
 				}
+				parsedStruct.Deprecated, parsedStruct.DeprecatedMessage = deprecation(doc.Text())
 
 				// Generate the full struct definition using AST
 				var defBuf bytes.Buffer
 				// Use go/format to properly format the struct definition
-				defBuf.WriteString(fmt.Sprintf("type %s struct {\n", t.Name))
+				defBuf.WriteString(fmt.Sprintf("type %s%s struct {\n", t.Name, formatTypeParams(typeSpec.TypeParams)))
 				for _, field := range structType.Fields.List {
 					// Format each field properly
 					defBuf.WriteString("\t")
@@ -392,58 +659,126 @@ func extractStructs(docPkg *doc.Package, ourPkg Package) ([]Struct, error) {
 				defBuf.WriteString("}")
 				parsedStruct.Definition = defBuf.String()
 
-				for _, fvalue := range structType.Fields.List {
-					name := ""
-					if len(fvalue.Names) > 0 {
-						name = fvalue.Names[0].Obj.Name
-					}
+				fields, err := extractStructFields(fset, structType.Fields, ourPkg)
+				if err != nil {
+					return nil, err
+				}
+				parsedStruct.Fields = append(parsedStruct.Fields, fields...)
 
-					field := Field{
-						Name: name,
-						Type: "",
-						Tag:  "",
+				structs = append(structs, parsedStruct)
+			}
+		}
+	}
+	return structs, nil
+}
 
-						TypeDetails: TypeDetails{},
-					}
+// extractStructFields extracts the fields of a struct declaration, one per line of the declaration:
+// a field declaring several names is named after the first one, with all of them in Names.
+func extractStructFields(fset *token.FileSet, fieldList *ast.FieldList, pkg Package) ([]Field, error) {
+	var fields []Field
+	for _, fvalue := range fieldList.List {
+		typeDetails, err := getFullType(fvalue.Type, pkg)
+		if err != nil {
+			return nil, err
+		}
 
-					if len(field.Name) > 0 {
-						field.Private = strings.ToLower(string(field.Name[0])) == string(field.Name[0])
-					}
+		field := newField(fset, fvalue, typeDetails)
+		for _, ident := range fvalue.Names {
+			field.Names = append(field.Names, ident.Name)
+		}
+		if len(field.Names) > 0 {
+			field.Name = field.Names[0]
+		}
+		if len(field.Name) > 0 {
+			field.Private = strings.ToLower(string(field.Name[0])) == string(field.Name[0])
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
 
-					if fvalue.Doc != nil {
-						field.Docs = getDocsForFieldAst(fvalue.Doc)
-					}
+// fieldNames returns every name declared by a field, its type name when it's embedded.
+// Names is ignored when Name was changed since the field was parsed.
+func fieldNames(f Field) []string {
+	if len(f.Names) > 0 && f.Names[0] == f.Name {
+		return f.Names
+	}
+	return []string{f.Name}
+}
 
-					if fvalue.Comment != nil {
-						field.Comment = cleanDocText(fvalue.Comment.Text())
-					}
+// extractFields extracts the fields of the inline struct types described by TypeDetails, one per name.
+// Their types are described like TypeDetails and they have no position.
+func extractFields(fieldList *ast.FieldList, pkg Package) ([]Field, error) {
+	var fields []Field
+	for _, fvalue := range fieldList.List {
+		typeDetails, err := getFullType(fvalue.Type, pkg)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(fvalue.Names))
+		for _, ident := range fvalue.Names {
+			names = append(names, ident.Name)
+		}
+		if len(names) == 0 {
+			names = append(names, embeddedFieldName(fvalue.Type))
+		}
+		for _, name := range names {
+			field := newField(nil, fvalue, typeDetails)
+			field.Name = name
+			if len(name) > 0 {
+				field.Private = strings.ToLower(string(name[0])) == string(name[0])
+			}
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
 
-					if fvalue.Tag != nil {
-						field.Tag = strings.Trim(fvalue.Tag.Value, "`")
-					}
+// newField returns the field declared by fvalue, named after its type when it's embedded.
+func newField(fset *token.FileSet, fvalue *ast.Field, typeDetails *TypeDetails) Field {
+	field := Field{
+		Type:     typeDetails.TypeName,
+		Embedded: len(fvalue.Names) == 0,
+		Pointer:  typeDetails.IsPointer,
+		Slice:    isSliceOrArray(typeDetails),
+		Position: newPosition(fset, fvalue),
 
-					typeDetails, err := getFullType(fvalue.Type, ourPkg)
-					if err != nil {
-						return nil, err
-					}
-					field.TypeDetails = *typeDetails
-					field.Pointer = typeDetails.IsPointer
-					field.Slice = typeDetails.IsSlice
+		TypeDetails: *typeDetails,
+	}
+	if field.Embedded {
+		field.Name = embeddedFieldName(fvalue.Type)
+	}
 
-					field.Type = typeDetails.TypeName
+	if fvalue.Doc != nil {
+		field.Docs = getDocsForFieldAst(fvalue.Doc)
+	}
 
-					parsedStruct.Fields = append(parsedStruct.Fields, field)
-				}
+	if fvalue.Comment != nil {
+		field.Comment = cleanDocText(docComment(fvalue.Comment).Text())
+	}
 
-				structs = append(structs, parsedStruct)
-			}
+	field.Directives = extractDirectives(fset, fvalue.Doc, fvalue.Comment)
+	field.Deprecated, field.DeprecatedMessage = deprecation(fvalue.Doc.Text())
+
+	if fvalue.Tag != nil {
+		field.Tag = strings.Trim(fvalue.Tag.Value, "`")
+		if tag, err := strconv.Unquote(fvalue.Tag.Value); err == nil {
+			field.Tags = parseStructTag(tag)
 		}
 	}
-	return structs, nil
+	return field
+}
+
+// isSliceOrArray reports whether t is a slice or an array, or a pointer to one.
+func isSliceOrArray(t *TypeDetails) bool {
+	for t.IsPointer && t.Elem != nil {
+		t = t.Elem
+	}
+	return t.IsSlice || t.IsArray
 }
 
 // extractInterfaces extracts interfaces from the provided documentation package.
-func extractInterfaces(docPkg *doc.Package, pkg Package) ([]Interface, error) {
+func extractInterfaces(fset *token.FileSet, docPkg *doc.Package, pkg Package) ([]Interface, error) {
 	var interfaces []Interface
 	for _, t := range docPkg.Types {
 		if t == nil || t.Decl == nil {
@@ -458,19 +793,36 @@ func extractInterfaces(docPkg *doc.Package, pkg Package) ([]Interface, error) {
 
 			interfaceType, ok := typeSpec.Type.(*ast.InterfaceType)
 			if ok {
+				doc := specDoc(typeSpec.Doc, t.Decl)
 				parsedInterface := Interface{
-					Name:    t.Name,
-					Methods: make([]Method, 0),
-					Docs:    getDocsForStruct(t.Doc),
+					Name:       t.Name,
+					TypeParams: extractTypeParams(typeSpec.TypeParams),
+					Methods:    make([]Method, 0),
+					Docs:       getDocsForStruct(docComment(doc).Text()),
+					Directives: extractDirectives(fset, doc, typeSpec.Comment),
+					Position:   newPosition(fset, typeSpec),
 
 					PtrPackage: &pkg,
 				}
+				parsedInterface.Deprecated, parsedInterface.DeprecatedMessage = deprecation(doc.Text())
 
 				// Generate the full interface definition
 				var defBuf bytes.Buffer
-				defBuf.WriteString(fmt.Sprintf("type %s interface {\n", t.Name))
+				defBuf.WriteString(fmt.Sprintf("type %s%s interface {\n", t.Name, formatTypeParams(typeSpec.TypeParams)))
 
 				for _, m := range interfaceType.Methods.List {
+					if len(m.Names) == 0 && isUnionElement(m.Type) {
+						// Constraint interfaces list the types they accept, e.g. ~int | ~string
+						parsedInterface.Unions = append(parsedInterface.Unions, unionTerms(m.Type))
+						defBuf.WriteString("\t" + exprToString(m.Type) + "\n")
+						continue
+					}
+					if len(m.Names) == 0 {
+						// Embedded interface, e.g. io.Reader
+						parsedInterface.Embeds = append(parsedInterface.Embeds, exprToString(m.Type))
+						defBuf.WriteString("\t" + exprToString(m.Type) + "\n")
+						continue
+					}
 					if funcType, ok := m.Type.(*ast.FuncType); ok {
 						// Generate method definition
 						methodDef := m.Names[0].Name + "(" + formatParams(funcType.Params, pkg) + ")"
@@ -484,14 +836,17 @@ func extractInterfaces(docPkg *doc.Package, pkg Package) ([]Interface, error) {
 						}
 
 						method := Method{
-							Name:    m.Names[0].Name,
-							Params:  extractParams(funcType.Params, pkg),
-							Returns: extractParams(funcType.Results, pkg),
-							Docs:    getDocsForFieldAst(m.Doc),
+							Name:       m.Names[0].Name,
+							Params:     extractParams(funcType.Params, pkg),
+							Returns:    extractParams(funcType.Results, pkg),
+							Docs:       getDocsForFieldAst(m.Doc),
+							Directives: extractDirectives(fset, m.Doc, m.Comment),
 							Signature: fmt.Sprintf("%s(%s) (%s)", m.Names[0].Name,
 								formatParams(funcType.Params, pkg), formatParams(funcType.Results, pkg)),
 							Definition: methodDef,
+							Position:   newPosition(fset, m),
 						}
+						method.Deprecated, method.DeprecatedMessage = deprecation(m.Doc.Text())
 
 						// Add method to definition buffer
 						defBuf.WriteString("\t")
@@ -528,31 +883,88 @@ func extractInterfaces(docPkg *doc.Package, pkg Package) ([]Interface, error) {
 	return interfaces, nil
 }
 
-// extractImports extracts unique imports from the provided package.
+// extractTypeDecls extracts the named types that are neither structs nor interfaces from the provided documentation package.
+func extractTypeDecls(fset *token.FileSet, docPkg *doc.Package, ourPkg Package) ([]TypeDecl, error) {
+	var typeDecls []TypeDecl
+	for _, t := range docPkg.Types {
+		if t == nil || t.Decl == nil {
+			return nil, errors.New("t or t.Decl is nil")
+		}
+
+		for _, spec := range t.Decl.Specs {
+			typeSpec, ok := spec.(*ast.TypeSpec)
+			if !ok {
+				return nil, errors.New("not a *ast.TypeSpec")
+			}
+
+			switch typeSpec.Type.(type) {
+			case *ast.StructType, *ast.InterfaceType:
+				continue
+			}
+
+			typeDetails, err := getFullType(typeSpec.Type, ourPkg)
+			if err != nil {
+				return nil, err
+			}
+
+			underlying := exprToString(typeSpec.Type)
+			definition := fmt.Sprintf("type %s%s %s", t.Name, formatTypeParams(typeSpec.TypeParams), underlying)
+			if typeSpec.Assign.IsValid() {
+				definition = fmt.Sprintf("type %s%s = %s", t.Name, formatTypeParams(typeSpec.TypeParams), underlying)
+			}
+
+			doc := specDoc(typeSpec.Doc, t.Decl)
+			typeDecl := TypeDecl{
+				Name:        t.Name,
+				TypeParams:  extractTypeParams(typeSpec.TypeParams),
+				Underlying:  underlying,
+				TypeDetails: *typeDetails,
+				IsAlias:     typeSpec.Assign.IsValid(),
+				Methods:     make([]Method, 0),
+				Docs:        getDocsForStruct(docComment(doc).Text()),
+				Directives:  extractDirectives(fset, doc, typeSpec.Comment),
+				Definition:  definition,
+				Position:    newPosition(fset, typeSpec),
+
+				PtrPackage: &ourPkg,
+			}
+			typeDecl.Deprecated, typeDecl.DeprecatedMessage = deprecation(doc.Text())
+			typeDecls = append(typeDecls, typeDecl)
+		}
+	}
+	return typeDecls, nil
+}
+
+// extractImports extracts unique imports from the provided package, with the alias they're
+// imported as, so that the qualifiers of the files can be resolved. A path imported under
+// different names is listed once per name.
 func extractImports(pkg *ast.Package) ([]Import, error) {
-	importSet := make(map[string]struct{})
+	importSet := make(map[Import]struct{})
 	for _, file := range pkg.Files {
-		for _, importSpec := range file.Imports {
-			importPath := strings.Trim(importSpec.Path.Value, "\"")
-			importSet[importPath] = struct{}{}
+		for _, imp := range fileImports(file) {
+			importSet[imp] = struct{}{} // PtrPackage isn't set yet
 		}
 	}
 
 	var imports []Import
 	for imp := range importSet {
-		imports = append(imports, Import{
-			Path: imp,
-		})
+		imports = append(imports, imp)
 	}
+	sort.Slice(imports, func(i, j int) bool {
+		if imports[i].Path != imports[j].Path {
+			return imports[i].Path < imports[j].Path
+		}
+		return imports[i].Name < imports[j].Name
+	})
 	return imports, nil
 }
 
 // extractConstantsVariables extracts constants and variables from the provided package.
-func extractConstantsVariables(pkg *ast.Package, ourPkg Package) ([]Constant, []Variable, error) {
+func extractConstantsVariables(fset *token.FileSet, pkg *ast.Package, ourPkg Package) ([]Constant, []Variable, error) {
 	var constants []Constant
 	var variables []Variable
 
-	for _, file := range pkg.Files {
+	for _, file := range sortedFiles(pkg) {
 		for _, decl := range file.Decls {
 			genDecl, ok := decl.(*ast.GenDecl)
 			if !ok {
@@ -566,15 +978,22 @@ func extractConstantsVariables(pkg *ast.Package, ourPkg Package) ([]Constant, []
 					if !ok {
 						continue
 					}
+					doc := specDoc(valSpec.Doc, genDecl)
 					for i, name := range valSpec.Names {
 						constant := Constant{
-							Name:  name.Name,
-							Value: "",
-							Docs:  getDocsForFieldAst(valSpec.Doc),
+							Name:       name.Name,
+							Value:      "",
+							Docs:       getDocsForFieldAst(doc),
+							Directives: extractDirectives(fset, doc, valSpec.Comment),
+							Position:   newPositionRange(fset, name.Pos(), valSpec.End()),
 						}
+						constant.Deprecated, constant.DeprecatedMessage = deprecation(doc.Text())
 						if i < len(valSpec.Values) {
 							constant.Value = exprToString(valSpec.Values[i])
 						}
+						if valSpec.Type != nil {
+							constant.Type = exprToString(valSpec.Type)
+						}
 						constants = append(constants, constant)
 					}
 				}
@@ -584,16 +1003,23 @@ func extractConstantsVariables(pkg *ast.Package, ourPkg Package) ([]Constant, []
 					if !ok {
 						continue
 					}
-					for _, name := range valSpec.Names {
+					doc := specDoc(valSpec.Doc, genDecl)
+					for i, name := range valSpec.Names {
 						varType := ""
 						if valSpec.Type != nil {
 							tmp, _ := getFullType(valSpec.Type, ourPkg)
 							varType = tmp.TypeName
 						}
 						variable := Variable{
-							Name: name.Name,
-							Type: varType,
-							Docs: getDocsForFieldAst(valSpec.Doc),
+							Name:       name.Name,
+							Type:       varType,
+							Docs:       getDocsForFieldAst(doc),
+							Directives: extractDirectives(fset, doc, valSpec.Comment),
+							Position:   newPositionRange(fset, name.Pos(), valSpec.End()),
+						}
+						variable.Deprecated, variable.DeprecatedMessage = deprecation(doc.Text())
+						if i < len(valSpec.Values) {
+							variable.Value = exprToString(valSpec.Values[i])
 						}
 						variables = append(variables, variable)
 					}
@@ -605,6 +1031,196 @@ func extractConstantsVariables(pkg *ast.Package, ourPkg Package) ([]Constant, []
 	return constants, variables, nil
 }
 
+// extractEnums extracts the const blocks whose constants are typed with a named type.
+// Values are resolved following the Go rules for iota and implicit repetition of the previous expression.
+func extractEnums(fset *token.FileSet, pkg *ast.Package) ([]Enum, error) {
+	var enums []Enum
+	known := make(map[string]constant.Value)
+	knownTypes := make(map[string]string)
+	for _, file := range sortedFiles(pkg) {
+		for _, decl := range file.Decls {
+			genDecl, ok := decl.(*ast.GenDecl)
+			if !ok || genDecl.Tok != token.CONST {
+				continue
+			}
+
+			var (
+				typeExpr   ast.Expr
+				valueExprs []ast.Expr
+				current    *Enum
+			)
+			for iota, spec := range genDecl.Specs {
+				valSpec, ok := spec.(*ast.ValueSpec)
+				if !ok {
+					continue
+				}
+				// A spec without type and values repeats the previous ones
+				if valSpec.Type != nil || len(valSpec.Values) > 0 {
+					typeExpr = valSpec.Type
+					valueExprs = valSpec.Values
+				}
+
+				typeName := ""
+				if isNamedType(typeExpr) {
+					typeName = exprToString(typeExpr)
+				} else if typeExpr == nil && len(valueExprs) > 0 {
+					// Untyped specs take the type of the constants they're built from, e.g. FlagA | FlagB
+					typeName = inferConstType(valueExprs[0], knownTypes)
+				}
+				if current != nil && current.Type != typeName {
+					enums = append(enums, *current)
+					current = nil
+				}
+
+				for i, name := range valSpec.Names {
+					member := EnumMember{
+						Name:     name.Name,
+						Docs:     getDocsForFieldAst(valSpec.Doc),
+						Position: newPositionRange(fset, name.Pos(), valSpec.End()),
+					}
+					if i < len(valueExprs) {
+						member.Expression = exprToString(valueExprs[i])
+						if value, ok := evalConstExpr(valueExprs[i], int64(iota), known); ok {
+							known[name.Name] = value
+							member.Value = formatConstValue(value)
+						}
+					}
+					if typeName == "" || name.Name == "_" {
+						continue
+					}
+					knownTypes[name.Name] = typeName
+					if current == nil {
+						current = &Enum{
+							Type:     typeName,
+							Docs:     getDocsForFieldAst(genDecl.Doc),
+							Position: newPosition(fset, genDecl),
+						}
+					}
+					current.Members = append(current.Members, member)
+				}
+			}
+			if current != nil {
+				enums = append(enums, *current)
+			}
+		}
+	}
+	return enums, nil
+}
+
+// inferConstType returns the named type of a constant expression without an explicit type,
+// taken from the typed constants or conversions it uses.
+func inferConstType(expr ast.Expr, knownTypes map[string]string) string {
+	typeName := ""
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if typeName != "" {
+			return false
+		}
+		switch e := n.(type) {
+		case *ast.CallExpr:
+			if isNamedType(e.Fun) {
+				typeName = exprToString(e.Fun)
+			}
+		case *ast.Ident:
+			typeName = knownTypes[e.Name]
+		}
+		return typeName == ""
+	})
+	return typeName
+}
+
+// isNamedType reports whether expr refers to a declared type rather than a predeclared one like int or string.
+func isNamedType(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return types.Universe.Lookup(e.Name) == nil
+	case *ast.SelectorExpr:
+		return true
+	}
+	return false
+}
+
+// evalConstExpr evaluates a constant expression, resolving iota and the constants already known.
+func evalConstExpr(expr ast.Expr, iota int64, known map[string]constant.Value) (value constant.Value, ok bool) {
+	// go/constant panics on operations that don't apply to the operands, e.g. code that doesn't type check
+	defer func() {
+		if recover() != nil {
+			value, ok = nil, false
+		}
+	}()
+
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		value := constant.MakeFromLiteral(e.Value, e.Kind, 0)
+		return value, value.Kind() != constant.Unknown
+	case *ast.Ident:
+		switch e.Name {
+		case "iota":
+			return constant.MakeInt64(iota), true
+		case "true", "false":
+			return constant.MakeBool(e.Name == "true"), true
+		}
+		value, ok := known[e.Name]
+		return value, ok
+	case *ast.ParenExpr:
+		return evalConstExpr(e.X, iota, known)
+	case *ast.CallExpr:
+		// Conversions such as Status(1) keep the value of their argument
+		if len(e.Args) != 1 {
+			return nil, false
+		}
+		return evalConstExpr(e.Args[0], iota, known)
+	case *ast.UnaryExpr:
+		x, ok := evalConstExpr(e.X, iota, known)
+		if !ok {
+			return nil, false
+		}
+		value := constant.UnaryOp(e.Op, x, 0)
+		return value, value.Kind() != constant.Unknown
+	case *ast.BinaryExpr:
+		x, ok := evalConstExpr(e.X, iota, known)
+		if !ok {
+			return nil, false
+		}
+		y, ok := evalConstExpr(e.Y, iota, known)
+		if !ok {
+			return nil, false
+		}
+		var value constant.Value
+		switch e.Op {
+		case token.SHL, token.SHR:
+			shift, ok := constant.Uint64Val(y)
+			if !ok {
+				return nil, false
+			}
+			value = constant.Shift(x, e.Op, uint(shift))
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			value = constant.MakeBool(constant.Compare(x, e.Op, y))
+		case token.QUO:
+			if x.Kind() == constant.Int && y.Kind() == constant.Int {
+				if constant.Sign(y) == 0 {
+					return nil, false
+				}
+				// Integer division, as opposed to the exact division of untyped constants
+				value = constant.BinaryOp(x, token.QUO_ASSIGN, y)
+			} else {
+				value = constant.BinaryOp(x, e.Op, y)
+			}
+		default:
+			value = constant.BinaryOp(x, e.Op, y)
+		}
+		return value, value.Kind() != constant.Unknown
+	}
+	return nil, false
+}
+
+// formatConstValue returns the Go representation of a constant value.
+func formatConstValue(value constant.Value) string {
+	if value.Kind() == constant.Float {
+		return value.String()
+	}
+	return value.ExactString()
+}
+
 func extractParams(fieldList *ast.FieldList, pkg Package) []Param {
 	if fieldList == nil {
 		return nil
@@ -642,6 +1258,106 @@ func formatParams(fields *ast.FieldList, pkg Package) string {
 	return strings.Join(paramStrings, ", ")
 }
 
+// extractTypeParams returns the type parameters declared by fieldList, e.g. [K comparable, V any].
+func extractTypeParams(fieldList *ast.FieldList) []TypeParam {
+	if fieldList == nil {
+		return nil
+	}
+	var params []TypeParam
+	for _, field := range fieldList.List {
+		constraint := exprToString(field.Type)
+		for _, name := range field.Names {
+			params = append(params, TypeParam{Name: name.Name, Constraint: constraint})
+		}
+	}
+	return params
+}
+
+// formatTypeParams returns the type parameter list of fieldList as written in a declaration,
+// e.g. "[K comparable, V any]", or "" when there are none.
+func formatTypeParams(fieldList *ast.FieldList) string {
+	if fieldList == nil || len(fieldList.List) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(fieldList.List))
+	for _, field := range fieldList.List {
+		names := make([]string, 0, len(field.Names))
+		for _, name := range field.Names {
+			names = append(names, name.Name)
+		}
+		parts = append(parts, strings.Join(names, ", ")+" "+exprToString(field.Type))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// receiverTypeParams returns the type parameters named by a generic receiver such as *Set[K].
+// The receiver doesn't repeat the constraints, they are filled in once the receiver type is known.
+func receiverTypeParams(expr ast.Expr) []TypeParam {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	var indices []ast.Expr
+	switch t := expr.(type) {
+	case *ast.IndexExpr:
+		indices = []ast.Expr{t.Index}
+	case *ast.IndexListExpr:
+		indices = t.Indices
+	}
+	var params []TypeParam
+	for _, index := range indices {
+		params = append(params, TypeParam{Name: exprToString(index)})
+	}
+	return params
+}
+
+// embeddedFieldName returns the implicit name of an embedded field, which is the name of its type
+// without pointer, package qualifier or type arguments (e.g. "Mutex" for *sync.Mutex).
+func embeddedFieldName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return embeddedFieldName(t.X)
+	case *ast.IndexExpr:
+		return embeddedFieldName(t.X)
+	case *ast.IndexListExpr:
+		return embeddedFieldName(t.X)
+	case *ast.SelectorExpr:
+		return t.Sel.Name
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+// isUnionElement reports whether an element embedded in an interface is a union or a single
+// type term (e.g. ~int | ~string, int or []byte) rather than an embedded interface.
+func isUnionElement(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BinaryExpr, *ast.UnaryExpr, *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.StarExpr, *ast.FuncType, *ast.StructType:
+		return true
+	case *ast.Ident:
+		obj, ok := types.Universe.Lookup(e.Name).(*types.TypeName)
+		return ok && !types.IsInterface(obj.Type())
+	}
+	return false
+}
+
+// unionTerms splits a union element such as ~int | ~string into its terms.
+func unionTerms(expr ast.Expr) []TypeTerm {
+	switch e := expr.(type) {
+	case *ast.BinaryExpr:
+		if e.Op == token.OR {
+			return append(unionTerms(e.X), unionTerms(e.Y)...)
+		}
+	case *ast.UnaryExpr:
+		if e.Op == token.TILDE {
+			return []TypeTerm{{Type: exprToString(e.X), Tilde: true}}
+		}
+	case *ast.ParenExpr:
+		return unionTerms(e.X)
+	}
+	return []TypeTerm{{Type: exprToString(expr)}}
+}
+
 func exprToString(expr ast.Expr) string {
 	var buf bytes.Buffer
 	err := printer.Fprint(&buf, token.NewFileSet(), expr)
@@ -690,6 +1406,9 @@ func getDocsForFieldAst(cg *ast.CommentGroup) []string {
 	}
 	docs := make([]string, 0, len(cg.List))
 	for _, v := range cg.List {
+		if _, ok := parseDirective(v.Text); ok {
+			continue
+		}
 		docs = append(docs, cleanDocText(v.Text))
 	}
 	return docs
@@ -755,10 +1474,9 @@ func getFullType(expr ast.Expr, pkg Package) (*TypeDetails, error) {
 
 	switch t := expr.(type) {
 	case *ast.Ident:
-		switch t.Name {
-		case "string", "int", "int32", "int64", "float32", "float64", "bool", "byte", "rune", "error":
+		if _, ok := types.Universe.Lookup(t.Name).(*types.TypeName); ok {
 			tr.IsBuiltin = true
-		default:
+		} else {
 			// TODO: need to know if it's apackage or a Type that needs the PackageName from pkg
 			if pkg.Package != "" {
 				tr.PackageName = &pkg.Package
@@ -768,9 +1486,6 @@ func getFullType(expr ast.Expr, pkg Package) (*TypeDetails, error) {
 		}
 		tr.TypeName = t.Name
 		tr.Type = &t.Name
-		// Optionally, set IsBuiltin based on known built-in types
-		// Example:
-		// tr.IsBuiltin = isBuiltinType(t.Name)
 
 	case *ast.SelectorExpr:
 		tr.IsExternal = true
@@ -806,6 +1521,7 @@ func getFullType(expr ast.Expr, pkg Package) (*TypeDetails, error) {
 		tr.Package = coallesce(tr.Package, innerFullType.Package)
 		tr.PackageName = coallesce(tr.PackageName, innerFullType.PackageName)
 		tr.Type = coallesce(tr.Type, innerFullType.Type)
+		tr.Elem = innerFullType
 
 	case *ast.IndexExpr:
 		// Handle single type parameter (legacy support)
@@ -844,13 +1560,19 @@ func getFullType(expr ast.Expr, pkg Package) (*TypeDetails, error) {
 		// Optionally, handle flags from generic parameters
 
 	case *ast.ArrayType:
-		tr.IsSlice = true
 		eltFullType, err := getFullType(t.Elt, pkg)
 		if err != nil {
 			return nil, err
 		}
-		tr.TypeName = "[]" + eltFullType.TypeName
+		if t.Len != nil {
+			tr.IsArray = true
+			tr.ArrayLen = exprToString(t.Len)
+		} else {
+			tr.IsSlice = true
+		}
+		tr.TypeName = "[" + tr.ArrayLen + "]" + eltFullType.TypeName
 		tr.Type = &eltFullType.TypeName
+		tr.Elem = eltFullType
 		tr.Package = coallesce(tr.Package, eltFullType.Package)
 		tr.PackageName = coallesce(tr.PackageName, eltFullType.PackageName)
 		// Optionally, propagate other flags from element type
@@ -861,10 +1583,13 @@ func getFullType(expr ast.Expr, pkg Package) (*TypeDetails, error) {
 		switch t.Dir {
 		case ast.RECV:
 			dir = "<-chan "
+			tr.ChanDir = "recv"
 		case ast.SEND:
 			dir = "chan<- "
+			tr.ChanDir = "send"
 		default:
 			dir = "chan "
+			tr.ChanDir = "both"
 		}
 		valueFullType, err := getFullType(t.Value, pkg)
 		if err != nil {
@@ -872,6 +1597,8 @@ func getFullType(expr ast.Expr, pkg Package) (*TypeDetails, error) {
 		}
 		tr.TypeName = fmt.Sprintf("%s%s", dir, valueFullType.TypeName)
 		tr.Type = &valueFullType.TypeName
+		tr.IsChan = true
+		tr.Elem = valueFullType
 		tr.Package = coallesce(tr.Package, valueFullType.Package)
 		tr.PackageName = coallesce(tr.PackageName, valueFullType.PackageName)
 		// Optionally, propagate other flags from value type
@@ -887,6 +1614,8 @@ func getFullType(expr ast.Expr, pkg Package) (*TypeDetails, error) {
 		}
 		tr.TypeName = fmt.Sprintf("map[%s]%s", keyFullType.TypeName, valueFullType.TypeName)
 		tr.IsMap = true
+		tr.Key = keyFullType
+		tr.Elem = valueFullType
 		tr.Type = &valueFullType.TypeName
 		tr.Package = coallesce(tr.Package, valueFullType.Package)
 		tr.PackageName = coallesce(tr.PackageName, valueFullType.PackageName)
@@ -904,6 +1633,9 @@ func getFullType(expr ast.Expr, pkg Package) (*TypeDetails, error) {
 		}
 		tr.TypeName = fmt.Sprintf("func(%s) (%s)", params, results)
 		tr.Type = &tr.TypeName
+		tr.IsFunc = true
+		tr.Params = extractParams(t.Params, pkg)
+		tr.Results = extractParams(t.Results, pkg)
 		// Func types may have their own flags if needed
 
 	case *ast.InterfaceType:
@@ -922,8 +1654,18 @@ func getFullType(expr ast.Expr, pkg Package) (*TypeDetails, error) {
 		}
 		tr.TypeName = fmt.Sprintf("struct{%s}", fieldsStr)
 		tr.Type = &tr.TypeName
+		tr.IsStruct = true
+		tr.Fields, err = extractFields(t.Fields, pkg)
+		if err != nil {
+			return nil, err
+		}
 		// Optionally, handle embedded fields or other flags
 
+	case *ast.UnaryExpr, *ast.BinaryExpr:
+		// Union and approximation terms of constraint interfaces, e.g. ~int | ~string
+		tr.TypeName = exprToString(t)
+		tr.Type = &tr.TypeName
+
 	case *ast.Ellipsis:
 		tmp := t // Direct type assertion
 		eltFullType, err := getFullType(tmp.Elt, pkg)
@@ -940,12 +1682,19 @@ func getFullType(expr ast.Expr, pkg Package) (*TypeDetails, error) {
 			}
 		}
 		tr.Type = &eltFullType.TypeName
-		// Adjust flags if necessary
+		tr.Elem = eltFullType
 
 	default:
 		return nil, fmt.Errorf("unsupported type: %T", expr)
 	}
 
+	if pkg.typed != nil {
+		// Package names in qualified identifiers have an invalid type
+		if t := pkg.typed.info.TypeOf(expr); t != nil && t != types.Typ[types.Invalid] {
+			refineTypeDetails(&tr, t, pkg.typed.pkg)
+		}
+	}
+
 	return &tr, nil
 }
 
@@ -1,7 +1,15 @@
 package codesurgeon
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 )
@@ -142,9 +150,12 @@ func TestParseDirectory(t *testing.T) {
 
 			f = firstStruct.Field("ArrayInt")
 			require.Equal(t, "ArrayInt", f.Name)
-			require.Equal(t, "[]int", f.Type)
+			require.Equal(t, "[3]int", f.Type)
 			require.Equal(t, false, f.Pointer)
 			require.Equal(t, true, f.Slice)
+			require.Equal(t, "[3]int", f.TypeDetails.TypeName)
+			require.True(t, f.TypeDetails.IsArray)
+			require.Equal(t, "3", f.TypeDetails.ArrayLen)
 
 			f = firstStruct.Field("SliceString")
 			require.Equal(t, "SliceString", f.Name)
@@ -945,3 +956,1437 @@ func TestSomeFuncReturns(t *testing.T) {
 	require.Equal(t, "error", someFunc.Returns[0].Type)
 	require.Equal(t, "*Person", someFunc.Returns[1].Type)
 }
+
+func TestParsePositions(t *testing.T) {
+	code := `package test
+
+// Person is a person.
+type Person struct {
+	Name string
+}
+
+func (p *Person) Greet() string {
+	return "hi " + p.Name
+}
+
+type Greeter interface {
+	Greet() string
+}
+
+func NewPerson() *Person {
+	return &Person{}
+}
+
+var DefaultName = "bob"
+
+const MaxAge = 120
+`
+	output, err := ParseString(code)
+	require.NoError(t, err)
+
+	parsed := newHelper(&output.Packages[0])
+
+	person := parsed.Struct("Person")
+	require.Equal(t, 4, person.Position.Line)
+	require.Equal(t, 6, person.Position.Column)
+	require.Equal(t, 6, person.Position.EndLine)
+	require.Equal(t, 5, person.Field("Name").Position.Line)
+	require.Equal(t, 2, person.Field("Name").Position.Column)
+
+	require.Len(t, person.Methods, 1)
+	greet := person.Methods[0]
+	require.Equal(t, 8, greet.Position.Line)
+	require.Equal(t, 10, greet.Position.EndLine)
+	require.Equal(t, code[greet.Position.Offset:greet.Position.EndOffset], "func (p *Person) Greet() string {\n\treturn \"hi \" + p.Name\n}")
+
+	greeter := parsed.Interface("Greeter")
+	require.Equal(t, 12, greeter.Position.Line)
+	require.Equal(t, 13, greeter.Methods[0].Position.Line)
+
+	require.Equal(t, 16, parsed.Function("NewPerson").Position.Line)
+	require.Equal(t, 20, parsed.Variable("DefaultName").Position.Line)
+	require.Equal(t, 22, parsed.Constant("MaxAge").Position.Line)
+	require.Equal(t, "22:7", parsed.Constant("MaxAge").Position.String())
+}
+
+func TestParseGenerics(t *testing.T) {
+	code := `package test
+
+type Number interface {
+	~int | ~int64 | float64
+}
+
+type Set[K comparable] struct {
+	items map[K]struct{}
+}
+
+func (s *Set[K]) Add(k K) {
+	s.items[k] = struct{}{}
+}
+
+type Pair[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+func (p Pair[K, V]) Swap() Pair[K, V] {
+	return p
+}
+
+func Map[T, U any](s []T, f func(T) U) []U {
+	return nil
+}
+
+func Sum[N Number](values ...N) N {
+	var total N
+	return total
+}
+`
+	output, err := ParseString(code)
+	require.NoError(t, err)
+
+	parsed := newHelper(&output.Packages[0])
+
+	set := parsed.Struct("Set")
+	require.Equal(t, []TypeParam{{Name: "K", Constraint: "comparable"}}, set.TypeParams)
+	require.Contains(t, set.Definition, "type Set[K comparable] struct {")
+	require.Len(t, set.Methods, 1)
+	require.Equal(t, "*Set[K]", set.Methods[0].Receiver)
+	require.Equal(t, "Set", set.Methods[0].ReceiverTypeName())
+	require.Equal(t, []TypeParam{{Name: "K", Constraint: "comparable"}}, set.Methods[0].TypeParams)
+
+	pair := parsed.Struct("Pair")
+	require.Equal(t, []TypeParam{{Name: "K", Constraint: "comparable"}, {Name: "V", Constraint: "any"}}, pair.TypeParams)
+	require.Len(t, pair.Methods, 1)
+	require.Equal(t, []TypeParam{{Name: "K", Constraint: "comparable"}, {Name: "V", Constraint: "any"}}, pair.Methods[0].TypeParams)
+
+	mapFn := parsed.Function("Map")
+	require.Equal(t, []TypeParam{{Name: "T", Constraint: "any"}, {Name: "U", Constraint: "any"}}, mapFn.TypeParams)
+	require.Equal(t, "Map[T, U any](s []T, f func(T) (U)) []U", mapFn.Signature)
+	require.Equal(t, "func Map[T, U any](s []T, f func(T) (U)) []U", mapFn.Definition)
+
+	sum := parsed.Function("Sum")
+	require.Equal(t, []TypeParam{{Name: "N", Constraint: "Number"}}, sum.TypeParams)
+
+	number := parsed.Interface("Number")
+	require.Equal(t, [][]TypeTerm{{{Type: "int", Tilde: true}, {Type: "int64", Tilde: true}, {Type: "float64"}}}, number.Unions)
+	require.Contains(t, number.Definition, "~int | ~int64 | float64")
+}
+
+func TestParseTypeDecls(t *testing.T) {
+	code := `package test
+
+import "net/http"
+
+// Status is the status of a job.
+type Status string
+
+func (s Status) String() string {
+	return string(s)
+}
+
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+type Index map[string][]int
+
+type Handler = http.Handler
+
+type List[T any] []T
+
+func (l List[T]) Len() int {
+	return len(l)
+}
+`
+	output, err := ParseString(code)
+	require.NoError(t, err)
+
+	types := map[string]TypeDecl{}
+	for _, td := range output.Packages[0].Types {
+		types[td.Name] = td
+	}
+	require.Len(t, types, 5)
+	require.Empty(t, output.Packages[0].Structs)
+
+	status := types["Status"]
+	require.Equal(t, "string", status.Underlying)
+	require.False(t, status.IsAlias)
+	require.Equal(t, []string{"Status is the status of a job."}, status.Docs)
+	require.Equal(t, "type Status string", status.Definition)
+	require.Len(t, status.Methods, 1)
+	require.Equal(t, "String", status.Methods[0].Name)
+
+	require.Equal(t, "func(w http.ResponseWriter, r *http.Request) error", types["HandlerFunc"].Underlying)
+	require.Equal(t, "map[string][]int", types["Index"].Underlying)
+	require.True(t, types["Index"].TypeDetails.IsMap)
+
+	handler := types["Handler"]
+	require.True(t, handler.IsAlias)
+	require.Equal(t, "http.Handler", handler.Underlying)
+	require.Equal(t, "type Handler = http.Handler", handler.Definition)
+
+	list := types["List"]
+	require.Equal(t, "type List[T any] []T", list.Definition)
+	require.Len(t, list.Methods, 1)
+	require.Equal(t, []TypeParam{{Name: "T", Constraint: "any"}}, list.Methods[0].TypeParams)
+
+	llm := PrettyPrint([]*ParsedInfo{output}, "llm", nil, true, true, true, true, true, true, false, false)
+	require.Contains(t, llm, "Type: Handler = http.Handler")
+	require.Contains(t, llm, "Type: Status (string)")
+	require.Contains(t, llm, "String() string")
+
+	grepIndex := PrettyPrint([]*ParsedInfo{output}, "grepindex", nil, true, true, true, true, true, true, false, false)
+	require.Contains(t, grepIndex, "type> Status (string) defined")
+}
+
+func TestParseEnums(t *testing.T) {
+	code := `package test
+
+type Status int
+
+// Statuses of a job.
+const (
+	StatusPending Status = iota // waiting
+	StatusRunning
+	_
+	StatusDone
+)
+
+type Color string
+
+const (
+	Red   Color = "red"
+	Green Color = "green"
+	Max         = 10
+)
+
+type Flag uint8
+
+const (
+	FlagA Flag = 1 << iota
+	FlagB
+	FlagC
+	FlagAll = FlagA | FlagB | FlagC
+)
+
+const Untyped = iota
+`
+	output, err := ParseString(code)
+	require.NoError(t, err)
+
+	enums := output.Packages[0].Enums
+	require.Len(t, enums, 3)
+
+	require.Equal(t, "Status", enums[0].Type)
+	require.Equal(t, []string{"Statuses of a job."}, enums[0].Docs)
+	require.Len(t, enums[0].Members, 3)
+	require.Equal(t, "StatusPending", enums[0].Members[0].Name)
+	require.Equal(t, "0", enums[0].Members[0].Value)
+	require.Equal(t, "iota", enums[0].Members[0].Expression)
+	require.Equal(t, "StatusRunning", enums[0].Members[1].Name)
+	require.Equal(t, "1", enums[0].Members[1].Value)
+	require.Equal(t, "StatusDone", enums[0].Members[2].Name)
+	require.Equal(t, "3", enums[0].Members[2].Value)
+	require.Equal(t, 10, enums[0].Members[2].Position.Line)
+
+	require.Equal(t, "Color", enums[1].Type)
+	require.Len(t, enums[1].Members, 2)
+	require.Equal(t, `"green"`, enums[1].Members[1].Value)
+
+	require.Equal(t, "Flag", enums[2].Type)
+	require.Len(t, enums[2].Members, 4)
+	require.Equal(t, "4", enums[2].Members[2].Value)
+	require.Equal(t, "1 << iota", enums[2].Members[2].Expression)
+	require.Equal(t, "FlagAll", enums[2].Members[3].Name)
+	require.Equal(t, "7", enums[2].Members[3].Value)
+
+	llm := PrettyPrint([]*ParsedInfo{output}, "llm", nil, true, true, true, true, true, true, false, false)
+	require.Contains(t, llm, "Enum: Status (StatusPending=0, StatusRunning=1, StatusDone=3)")
+}
+
+func TestParseEmbeddingAndMethodSets(t *testing.T) {
+	code := `package test
+
+import (
+	"io"
+	"sync"
+)
+
+type Base struct {
+	ID string
+}
+
+func (b Base) GetID() string { return b.ID }
+
+func (b *Base) SetID(id string) { b.ID = id }
+
+type Named interface {
+	Name() string
+}
+
+type Entity interface {
+	Named
+	io.Closer
+	Kind() string
+}
+
+type User struct {
+	Base
+	*sync.Mutex
+	Named
+	Email string
+}
+
+func (u User) Kind() string { return "user" }
+
+func (u User) GetID() string { return "user-" + u.ID }
+`
+	output, err := ParseString(code)
+	require.NoError(t, err)
+
+	parsed := newHelper(&output.Packages[0])
+
+	user := parsed.Struct("User")
+	base := user.Field("Base")
+	require.True(t, base.Embedded)
+	require.Equal(t, "Base", base.Type)
+	mutex := user.Field("Mutex")
+	require.True(t, mutex.Embedded)
+	require.Equal(t, "*sync.Mutex", mutex.Type)
+	require.False(t, user.Field("Email").Embedded)
+
+	methodSet := map[string]MethodSetEntry{}
+	for _, m := range user.MethodSet {
+		methodSet[m.Name] = m
+	}
+	require.Len(t, methodSet, 4)
+	require.Empty(t, methodSet["GetID"].Via, "GetID declared on User shadows Base.GetID")
+	require.Equal(t, "User", methodSet["GetID"].Receiver)
+	require.Equal(t, []string{"Base"}, methodSet["SetID"].Via)
+	require.True(t, methodSet["SetID"].Pointer)
+	require.Equal(t, []string{"Named"}, methodSet["Name"].Via)
+	require.False(t, methodSet["Kind"].Pointer)
+
+	entity := parsed.Interface("Entity")
+	require.Equal(t, []string{"Named", "io.Closer"}, entity.Embeds)
+	require.Contains(t, entity.Definition, "\tio.Closer\n")
+	require.Len(t, entity.MethodSet, 2)
+	require.Equal(t, "Kind", entity.MethodSet[0].Name)
+	require.Equal(t, "Name", entity.MethodSet[1].Name)
+	require.Equal(t, "Named", entity.MethodSet[1].Receiver)
+}
+
+func TestParseMethodSetsThroughPointerEmbed(t *testing.T) {
+	code := `package test
+
+type C struct{}
+
+func (c *C) Reset() {}
+
+func (c C) String() string { return "" }
+
+type B struct {
+	C
+}
+
+type A struct {
+	*B
+}
+`
+	output, err := ParseString(code)
+	require.NoError(t, err)
+
+	parsed := newHelper(&output.Packages[0])
+
+	methodSet := map[string]MethodSetEntry{}
+	for _, m := range parsed.Struct("A").MethodSet {
+		methodSet[m.Name] = m
+	}
+	require.Len(t, methodSet, 2)
+	require.Equal(t, []string{"B", "C"}, methodSet["Reset"].Via)
+	require.False(t, methodSet["Reset"].Pointer, "C is reached through *B so (*C).Reset is in the method set of A")
+	require.False(t, methodSet["String"].Pointer)
+
+	methodSet = map[string]MethodSetEntry{}
+	for _, m := range parsed.Struct("B").MethodSet {
+		methodSet[m.Name] = m
+	}
+	require.True(t, methodSet["Reset"].Pointer)
+}
+
+func TestParseDirectoryRecursiveMethodSets(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "base"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "base", "base.go"), []byte(`package base
+
+type Model struct{}
+
+func (m *Model) Save() error { return nil }
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.go"), []byte(`package app
+
+import "example.com/app/base"
+
+type Post struct {
+	base.Model
+	Title string
+}
+`), 0644))
+
+	infos, err := ParseDirectoryRecursive(dir)
+	require.NoError(t, err)
+
+	var post *Struct
+	for _, info := range infos {
+		for _, pkg := range info.Packages {
+			for i := range pkg.Structs {
+				if pkg.Structs[i].Name == "Post" {
+					post = &pkg.Structs[i]
+				}
+			}
+		}
+	}
+	require.NotNil(t, post)
+	require.Len(t, post.MethodSet, 1)
+	require.Equal(t, "Save", post.MethodSet[0].Name)
+	require.Equal(t, "base", post.MethodSet[0].Package)
+	require.Equal(t, []string{"Model"}, post.MethodSet[0].Via)
+}
+
+func TestParseModule(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.21\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "model"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "model", "model.go"), []byte(`package model
+
+type Item struct {
+	Name  string
+	Price uint16
+}
+`), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "shop.go"), []byte(`package shop
+
+import (
+	m "example.com/shop/model"
+	"sync"
+)
+
+type Cart struct {
+	Items   []*m.Item
+	Lock    *sync.Mutex
+	Count   int8
+	Err     error
+	Lookup  map[string]m.Item
+}
+`), 0644))
+
+	infos, err := ParseModule(context.Background(), "./...", ParseOptions{Dir: dir})
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+
+	var cart helperField
+	for _, info := range infos {
+		if info.Packages[0].Package == "shop" {
+			cart = newHelper(&info.Packages[0]).Struct("Cart")
+			require.Equal(t, "example.com/shop", info.Modules[0].RootModuleName)
+			require.Equal(t, dir, info.Directory)
+		}
+	}
+
+	items := cart.Field("Items").TypeDetails
+	require.Equal(t, "[]*m.Item", items.TypeName)
+	require.Equal(t, "slice", items.Kind)
+	require.Equal(t, "example.com/shop/model", *items.Package)
+	require.Equal(t, "model", *items.PackageName)
+	require.Equal(t, "Item", *items.Type)
+	require.True(t, items.IsExternal)
+	require.Equal(t, "example.com/shop/model", *items.TypeReferences[0].Package)
+
+	lock := cart.Field("Lock").TypeDetails
+	require.Equal(t, "pointer", lock.Kind)
+	require.Equal(t, "sync", *lock.Package)
+
+	count := cart.Field("Count").TypeDetails
+	require.True(t, count.IsBuiltin)
+	require.Equal(t, "basic", count.Kind)
+	require.Nil(t, count.Package)
+
+	errField := cart.Field("Err").TypeDetails
+	require.True(t, errField.IsBuiltin)
+	require.Equal(t, "interface", errField.Kind)
+
+	lookup := cart.Field("Lookup").TypeDetails
+	require.Equal(t, "map", lookup.Kind)
+	require.Equal(t, "example.com/shop/model", *lookup.Package)
+}
+
+func TestParseCalls(t *testing.T) {
+	src := `package shop
+
+import (
+	"fmt"
+	str "strings"
+)
+
+type Cart struct {
+	store Store
+}
+
+type Store interface {
+	Save(name string) error
+}
+
+func Normalize(name string) string {
+	return str.ToLower(name)
+}
+
+func (c *Cart) Add(name string) error {
+	if len(name) == 0 {
+		return fmt.Errorf("empty name")
+	}
+	c.log(Normalize(name))
+	return c.store.Save(name)
+}
+
+func (c *Cart) log(msg string) {}
+`
+	parsedInfo, err := ParseString(src)
+	require.NoError(t, err)
+	h := newHelper(&parsedInfo.Packages[0])
+
+	normalize := h.Function("Normalize")
+	require.Len(t, normalize.Calls, 1)
+	require.Equal(t, "strings", normalize.Calls[0].Package)
+	require.Equal(t, "ToLower", normalize.Calls[0].Name)
+	require.Equal(t, "str.ToLower", normalize.Calls[0].Callee)
+	require.Equal(t, 17, normalize.Calls[0].Position.Line)
+
+	add := h.Struct("Cart").Methods[0]
+	require.Equal(t, "Add", add.Name)
+	require.Len(t, add.Calls, 4) // len is a builtin
+	require.Equal(t, CallRef{Package: "fmt", Name: "Errorf", Callee: "fmt.Errorf"}, withoutPosition(add.Calls[0]))
+	require.Equal(t, CallRef{Package: "shop", Receiver: "*Cart", Name: "log", Callee: "c.log"}, withoutPosition(add.Calls[1]))
+	require.Equal(t, CallRef{Package: "shop", Name: "Normalize", Callee: "Normalize"}, withoutPosition(add.Calls[2]))
+	require.Equal(t, CallRef{Name: "Save", Callee: "c.store.Save"}, withoutPosition(add.Calls[3]))
+
+	t.Run("Typed", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.21\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "shop.go"), []byte(src), 0644))
+
+		infos, err := ParseModule(context.Background(), ".", ParseOptions{Dir: dir})
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		h := newHelper(&infos[0].Packages[0])
+
+		add := h.Struct("Cart").Methods[0]
+		require.Len(t, add.Calls, 4)
+		require.Equal(t, CallRef{Package: "fmt", Name: "Errorf", Callee: "fmt.Errorf"}, withoutPosition(add.Calls[0]))
+		require.Equal(t, CallRef{Package: "example.com/shop", Receiver: "*Cart", Name: "log", Callee: "c.log"}, withoutPosition(add.Calls[1]))
+		require.Equal(t, CallRef{Package: "example.com/shop", Name: "Normalize", Callee: "Normalize"}, withoutPosition(add.Calls[2]))
+		// The receiver of calls through a field is resolved
+		require.Equal(t, CallRef{Package: "example.com/shop", Receiver: "Store", Name: "Save", Callee: "c.store.Save"}, withoutPosition(add.Calls[3]))
+	})
+}
+
+func withoutPosition(call CallRef) CallRef {
+	call.Position = Position{}
+	return call
+}
+
+func TestParseImplementations(t *testing.T) {
+	parsedInfo, err := ParseString(`package store
+
+import "io"
+
+type Store interface {
+	Save(name string, data []byte) error
+	Close() error
+}
+
+type ReadStore interface {
+	io.Reader
+	Close() error
+}
+
+type Number interface {
+	~int | ~float64
+}
+
+type MemStore struct{}
+
+func (m MemStore) Save(key string, value []byte) error { return nil }
+func (m *MemStore) Close() error                       { return nil }
+
+type FileStore struct{}
+
+func (f FileStore) Save(path string, b []byte) error { return nil }
+func (f FileStore) Close() error                      { return nil }
+
+type Cached struct {
+	*MemStore
+}
+
+type BadStore struct{}
+
+func (b BadStore) Save(name string) error { return nil }
+func (b BadStore) Close() error           { return nil }
+`)
+	require.NoError(t, err)
+	h := newHelper(&parsedInfo.Packages[0])
+
+	require.Equal(t, []Implementation{{Name: "Store", Package: "store", Pointer: true}}, h.Struct("MemStore").Implements)
+	require.Equal(t, []Implementation{{Name: "Store", Package: "store"}}, h.Struct("FileStore").Implements)
+	require.Equal(t, []Implementation{{Name: "Store", Package: "store"}}, h.Struct("Cached").Implements, "promoted through *MemStore")
+	require.Empty(t, h.Struct("BadStore").Implements, "Save has a different signature")
+
+	require.Equal(t, []Implementation{
+		{Name: "Cached", Package: "store"},
+		{Name: "FileStore", Package: "store"},
+		{Name: "MemStore", Package: "store", Pointer: true},
+	}, h.Interface("Store").Implementers)
+	require.Empty(t, h.Interface("ReadStore").Implementers, "io.Reader is outside of the parsed module")
+	require.Empty(t, h.Interface("Number").Implementers)
+
+	t.Run("AcrossPackages", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/app\n\ngo 1.21\n"), 0644))
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "model"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "model", "model.go"), []byte(`package model
+
+type Item struct{}
+
+type Repository interface {
+	Find(id int) (*Item, error)
+}
+`), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "app.go"), []byte(`package app
+
+import "example.com/app/model"
+
+type Item struct{}
+
+type SQLRepository struct{}
+
+func (r *SQLRepository) Find(id int) (*model.Item, error) { return nil, nil }
+
+type LocalRepository struct{}
+
+func (r *LocalRepository) Find(id int) (*Item, error) { return nil, nil }
+`), 0644))
+
+		infos, err := ParseDirectoryRecursive(dir)
+		require.NoError(t, err)
+
+		var repository *Interface
+		for _, info := range infos {
+			for _, pkg := range info.Packages {
+				for i := range pkg.Interfaces {
+					if pkg.Interfaces[i].Name == "Repository" {
+						repository = &pkg.Interfaces[i]
+					}
+				}
+			}
+		}
+		require.NotNil(t, repository)
+		require.Equal(t, []Implementation{{Name: "SQLRepository", Package: "example.com/app", Pointer: true}}, repository.Implementers)
+	})
+
+	t.Run("AliasedImport", func(t *testing.T) {
+		dir := t.TempDir()
+		write := func(name, content string) {
+			require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+		}
+		write("go.mod", "module example.com/shop\n\ngo 1.21\n")
+		write("model/model.go", `package model
+
+type Item struct{}
+
+type Finder interface {
+	Find(id int) (*Item, error)
+}
+
+type Base struct{}
+
+func (b *Base) Save() error { return nil }
+`)
+		write("repo/repo.go", `package repo
+
+import m "example.com/shop/model"
+
+type SQL struct {
+	*m.Base
+}
+
+func (r *SQL) Find(id int) (*m.Item, error) { return nil, nil }
+`)
+
+		infos, err := ParseModules(context.Background(), dir, ParseOptions{})
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		var sql Struct
+		for i := range infos[0].Packages {
+			pkg := &infos[0].Packages[i]
+			if pkg.Package == "repo" {
+				require.Equal(t, []Import{{Name: "m", Path: "example.com/shop/model"}}, pkg.Imports)
+				sql = newHelper(pkg).Struct("SQL").Struct
+			}
+		}
+		require.Equal(t, []Implementation{{Name: "Finder", Package: "example.com/shop/model", Pointer: true}}, sql.Implements)
+		require.Len(t, sql.MethodSet, 2)
+		require.Equal(t, "Save", sql.MethodSet[1].Name, "promoted through the aliased package")
+	})
+}
+
+func TestParseFilesAndBuildConstraints(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("go.mod", "module example.com/shop\n\ngo 1.21\n")
+	write("shop.go", "package shop\n\nfunc Buy() {}\n")
+	write("shop_windows.go", "package shop\n\nfunc Console() {}\n")
+	write("pro.go", "//go:build pro && !windows\n\npackage shop\n\nfunc Discount() {}\n")
+	write("legacy.go", "// +build ignore\n\npackage shop\n\nfunc Legacy() {}\n")
+	write("shop_test.go", "package shop\n\nfunc helper() {}\n")
+	write("external_test.go", "package shop_test\n\nfunc TestBuy() {}\n")
+
+	parsed, err := ParseDirectory(dir)
+	require.NoError(t, err)
+	require.Len(t, parsed.Packages, 2)
+
+	packages := map[string]Package{}
+	for _, pkg := range parsed.Packages {
+		packages[pkg.Package] = pkg
+	}
+	shop := packages["shop"]
+	require.False(t, shop.IsTest)
+	require.Equal(t, []string{"+build"}, directiveNames(shop.Files[0].Directives))
+	require.Equal(t, []string{"go:build"}, directiveNames(shop.Files[1].Directives))
+	for i := range shop.Files {
+		shop.Files[i].Directives = nil
+	}
+	require.Equal(t, []SourceFile{
+		{Name: filepath.Join(dir, "legacy.go"), BuildConstraint: "ignore"},
+		{Name: filepath.Join(dir, "pro.go"), BuildConstraint: "pro && !windows"},
+		{Name: filepath.Join(dir, "shop.go")},
+		{Name: filepath.Join(dir, "shop_test.go"), IsTest: true},
+		{Name: filepath.Join(dir, "shop_windows.go")},
+	}, shop.Files)
+
+	helper := newHelper(&shop).Function("helper")
+	file, ok := shop.File(helper.Position.File)
+	require.True(t, ok)
+	require.True(t, file.IsTest)
+
+	require.True(t, packages["shop_test"].IsTest)
+
+	functionNames := func(parsed *ParsedInfo) []string {
+		var names []string
+		for _, pkg := range parsed.Packages {
+			for _, fn := range pkg.Functions {
+				names = append(names, fn.Name)
+			}
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	parsed, err = ParseDirectoryWithOptions(dir, ParseOptions{GOOS: "linux", BuildTags: []string{"pro"}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Buy", "Discount"}, functionNames(parsed))
+
+	parsed, err = ParseDirectoryWithOptions(dir, ParseOptions{GOOS: "windows", BuildTags: []string{"pro"}, Tests: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"Buy", "Console", "TestBuy", "helper"}, functionNames(parsed))
+}
+
+func TestParseModules(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("go.mod", "module example.com/mono\n\ngo 1.21\n")
+	write("mono.go", "package mono\n\nimport \"example.com/mono/base\"\n\ntype Service struct {\n\tbase.Model\n}\n")
+	write("base/base.go", "package base\n\ntype Model struct{}\n\nfunc (m *Model) Save() error { return nil }\n")
+	write("broken/broken.go", "package broken\n\nfunc Broken( {\n")
+	write("testdata/data.go", "package testdata\n")
+	write(".hidden/hidden.go", "package hidden\n")
+	write("tools/go.mod", "module example.com/tools\n\ngo 1.21\n")
+	write("tools/gen/gen.go", "package gen\n\nfunc Generate() {}\n")
+
+	infos, err := ParseModules(context.Background(), dir, ParseOptions{Workers: 2})
+	require.NoError(t, err)
+	require.Len(t, infos, 2)
+
+	mono := infos[0]
+	require.Equal(t, dir, mono.Directory)
+	require.Len(t, mono.Modules, 2)
+	require.Equal(t, "example.com/mono/", mono.Modules[0].FullName)
+	require.Equal(t, "example.com/mono/base", mono.Modules[1].FullName)
+	require.Len(t, mono.Packages, 2)
+	require.Len(t, mono.Errors, 1)
+	require.Equal(t, filepath.Join(dir, "broken"), mono.Errors[0].Directory)
+
+	service := newHelper(&mono.Packages[0]).Struct("Service")
+	require.Len(t, service.MethodSet, 1, "promoted across the packages of the module")
+	require.Equal(t, "Save", service.MethodSet[0].Name)
+
+	tools := infos[1]
+	require.Equal(t, filepath.Join(dir, "tools"), tools.Directory)
+	require.Len(t, tools.Modules, 1)
+	require.Equal(t, "example.com/tools/gen", tools.Modules[0].FullName)
+	require.Empty(t, tools.Errors)
+}
+
+func TestWalkPackages(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("go.mod", "module example.com/mono\n\ngo 1.21\n")
+	write("mono.go", "package mono\n\ntype Service struct {\n\tModel\n}\n\ntype Model struct{}\n\nfunc (m *Model) Save() error { return nil }\n")
+	write("base/base.go", "package base\n\ntype Model struct{}\n")
+	write("broken/broken.go", "package broken\n\nfunc Broken( {\n")
+	write("testdata/data.go", "package testdata\n")
+	write("windows/windows.go", "//go:build windows\n\npackage windows\n")
+	write("zoo/zoo.go", "package zoo\n")
+
+	var dirs []string
+	var errs []PackageError
+	err := WalkPackages(context.Background(), dir, ParseOptions{Workers: 3, GOOS: "linux"}, func(info *ParsedInfo) error {
+		dirs = append(dirs, info.Directory)
+		errs = append(errs, info.Errors...)
+		if info.Directory == dir {
+			service := newHelper(&info.Packages[0]).Struct("Service")
+			require.Len(t, service.MethodSet, 1, "promoted within the package")
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{dir, filepath.Join(dir, "base"), filepath.Join(dir, "broken"), filepath.Join(dir, "zoo")}, dirs, "in the order of the walk")
+	require.Len(t, errs, 1)
+	require.Equal(t, filepath.Join(dir, "broken"), errs[0].Directory)
+
+	// Walking stops at the first error of fn
+	stop := errors.New("stop")
+	var walked int
+	err = WalkPackages(context.Background(), dir, ParseOptions{Workers: 2}, func(info *ParsedInfo) error {
+		walked++
+		return stop
+	})
+	require.ErrorIs(t, err, stop)
+	require.Equal(t, 1, walked)
+}
+
+func TestParseCache(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("go.mod", "module example.com/shop\n\ngo 1.21\n")
+	write("cart.go", "package shop\n\n// Cart holds items.\ntype Cart struct {\n\tItems []string\n}\n")
+	write("cart_methods.go", "package shop\n\nfunc (c *Cart) Add(item string) {\n\tc.Items = append(c.Items, item)\n}\n")
+
+	uncached, err := ParseDirectory(dir)
+	require.NoError(t, err)
+
+	cacheDir := filepath.Join(dir, DefaultParseCacheDir)
+	cache, err := OpenModuleParseCache(dir)
+	require.NoError(t, err)
+	require.Equal(t, cacheDir, cache.Dir())
+	require.NoDirExists(t, cacheDir, "opening a cache doesn't create its directory")
+	stats, err := cache.Stats()
+	require.NoError(t, err)
+	require.Zero(t, stats.Entries)
+
+	parsed, err := ParseDirectoryWithOptions(dir, ParseOptions{AllFiles: true, Cache: cache})
+	require.NoError(t, err)
+	stats, err = cache.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 1, stats.Entries)
+	require.EqualValues(t, 0, stats.Hits)
+	require.EqualValues(t, 1, stats.Misses)
+
+	cart := newHelper(&parsed.Packages[0]).Struct("Cart")
+	require.Len(t, cart.Methods, 1, "methods declared in another file are attached")
+	require.Equal(t, uncached.Packages[0].Structs[0].Methods[0].Definition, cart.Methods[0].Definition)
+	require.Equal(t, uncached.Packages[0].Files, parsed.Packages[0].Files)
+	require.Equal(t, uncached.Modules[0].FullName, parsed.Modules[0].FullName)
+
+	// A new run parses the packages whose files changed
+	write("cart_methods.go", "package shop\n\nfunc (c *Cart) Add(item string) {}\n\nfunc (c *Cart) Len() int { return len(c.Items) }\n")
+	cache, err = OpenParseCache(cacheDir)
+	require.NoError(t, err)
+	infos, err := ParseModules(context.Background(), dir, ParseOptions{AllFiles: true, Cache: cache})
+	require.NoError(t, err)
+	require.Len(t, infos, 1)
+	cached := &infos[0].Packages[0].Structs[0]
+	require.Len(t, cached.Methods, 2)
+	require.NotNil(t, cached.PtrPackage, "back-references are set on entries read from the cache")
+	require.Equal(t, "shop", cached.PtrPackage.Package)
+	for _, m := range cached.Methods {
+		require.NotNil(t, m.PtrStruct)
+		require.Equal(t, "Cart", m.PtrStruct.Name)
+	}
+	stats, err = cache.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.Entries)
+	require.EqualValues(t, 0, stats.Hits)
+	require.EqualValues(t, 1, stats.Misses)
+
+	// And reads the others from the cache
+	infos, err = ParseModules(context.Background(), dir, ParseOptions{AllFiles: true, Cache: cache})
+	require.NoError(t, err)
+	require.Len(t, infos[0].Packages[0].Structs[0].Methods, 2)
+	stats, err = cache.Stats()
+	require.NoError(t, err)
+	require.Equal(t, 2, stats.Entries)
+	require.EqualValues(t, 1, stats.Hits)
+	require.EqualValues(t, 1, stats.Misses)
+
+	require.NoError(t, cache.Clear())
+	stats, err = cache.Stats()
+	require.NoError(t, err)
+	require.Zero(t, stats.Entries)
+}
+
+func TestParseCache_CrossFileEnums(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("go.mod", "module example.com/shop\n\ngo 1.21\n")
+	write("color.go", "package shop\n\n// Color of an item.\ntype Color int\n\nconst Base = 10\n")
+	write("colors.go", "package shop\n\nconst (\n\tRed Color = iota + Base\n\tGreen\n\tBlue\n)\n")
+	write("sizes.go", "package shop\n\ntype Size string\n\nconst (\n\tSmall Size = \"s\"\n\tLarge Size = \"l\"\n)\n")
+
+	uncached, err := ParseDirectoryWithOptions(dir, ParseOptions{AllFiles: true})
+	require.NoError(t, err)
+	want, err := json.Marshal(uncached)
+	require.NoError(t, err)
+
+	cache, err := OpenModuleParseCache(dir)
+	require.NoError(t, err)
+	for _, run := range []string{"stored", "read"} {
+		parsed, err := ParseDirectoryWithOptions(dir, ParseOptions{AllFiles: true, Cache: cache})
+		require.NoError(t, err)
+		got, err := json.Marshal(parsed)
+		require.NoError(t, err)
+		require.JSONEq(t, string(want), string(got), run)
+	}
+	stats, err := cache.Stats()
+	require.NoError(t, err)
+	require.EqualValues(t, 1, stats.Hits)
+
+	var green EnumMember
+	for _, e := range uncached.Packages[0].Enums {
+		if e.Type == "Color" {
+			for _, m := range e.Members {
+				if m.Name == "Green" {
+					green = m
+				}
+			}
+		}
+	}
+	require.Equal(t, "11", green.Value, "members typed in another file belong to the enum")
+}
+
+func TestParseTolerant(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("go.mod", "module example.com/shop\n\ngo 1.21\n")
+	write("cart.go", "package shop\n\n// Cart holds items.\ntype Cart struct {\n\tItems []string\n}\n")
+	write("broken.go", `package shop
+
+type Item struct {
+	Name string
+	Price []
+}
+
+type Order struct {
+	Cart *Cart
+}
+
+func (c *Cart) Add(item string) {
+	c.Items = append(c.Items, item)
+	c.Items[] = item
+}
+
+func Total(o Order) int {
+	return len(o.Cart.Items)
+}
+
+var Default = 
+`)
+	write("garbage.go", "this is not go\n")
+
+	_, err := ParseDirectory(dir)
+	require.Error(t, err)
+
+	check := func(t *testing.T, info *ParsedInfo) {
+		require.Len(t, info.Packages, 1)
+		h := newHelper(&info.Packages[0])
+		require.Equal(t, "Cart", h.Struct("Cart").Name)
+		require.Equal(t, "Order", h.Struct("Order").Name)
+		require.Empty(t, h.Struct("Item").Name, "incomplete declarations are skipped")
+		require.Len(t, h.Struct("Cart").Methods, 1, "syntax errors in bodies don't drop the function")
+		require.Equal(t, "Total", h.Function("Total").Name)
+
+		var errs, warnings []Diagnostic
+		for _, d := range info.Diagnostics {
+			require.Equal(t, d.File, d.Position.File)
+			switch d.Severity {
+			case SeverityError:
+				errs = append(errs, d)
+			case SeverityWarning:
+				warnings = append(warnings, d)
+			}
+		}
+		require.NotEmpty(t, errs)
+		lines := make(map[string][]int)
+		for _, d := range errs {
+			lines[filepath.Base(d.File)] = append(lines[filepath.Base(d.File)], d.Position.Line)
+		}
+		require.Equal(t, []int{5, 14, 21, 21}, lines["broken.go"])
+		require.NotEmpty(t, lines["garbage.go"], "files without package clause only get diagnostics")
+		require.Len(t, warnings, 2)
+		require.Equal(t, "type Item skipped: incomplete declaration", warnings[0].Message)
+		require.Equal(t, 3, warnings[0].Position.Line)
+		require.Equal(t, "var Default skipped: incomplete declaration", warnings[1].Message)
+	}
+
+	t.Run("Directory", func(t *testing.T) {
+		info, err := ParseDirectoryWithOptions(dir, ParseOptions{AllFiles: true, Tolerant: true})
+		require.NoError(t, err)
+		check(t, info)
+	})
+
+	t.Run("Modules", func(t *testing.T) {
+		cache, err := OpenModuleParseCache(dir)
+		require.NoError(t, err)
+		infos, err := ParseModules(context.Background(), dir, ParseOptions{AllFiles: true, Tolerant: true, Cache: cache})
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		require.Empty(t, infos[0].Errors)
+		check(t, infos[0])
+
+		stats, err := cache.Stats()
+		require.NoError(t, err)
+		require.Zero(t, stats.Entries, "packages with syntax errors aren't cached")
+	})
+}
+
+func TestParseFSAndOverlay(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":           {Data: []byte("module example.com/shop\n\ngo 1.21\n")},
+		"cart.go":          {Data: []byte("package shop\n\n// Cart holds items.\ntype Cart struct {\n\tItems []string\n}\n")},
+		"model/item.go":    {Data: []byte("package model\n\ntype Item struct {\n\tName string\n}\n")},
+		"model/item_ws.go": {Data: []byte("//go:build wasm\n\npackage model\n\ntype Wasm struct{}\n")},
+		"testdata/x.go":    {Data: []byte("package x\n")},
+	}
+
+	t.Run("FS", func(t *testing.T) {
+		infos, err := ParseFS(fsys, ".", ParseOptions{})
+		require.NoError(t, err)
+		require.Len(t, infos, 2)
+		require.Equal(t, "example.com/shop/", infos[0].Modules[0].FullName)
+		require.Equal(t, "example.com/shop/model", infos[1].Modules[0].FullName)
+		require.Equal(t, "cart.go", newHelper(&infos[0].Packages[0]).Struct("Cart").Position.File)
+		model := newHelper(&infos[1].Packages[0])
+		require.Equal(t, "Item", model.Struct("Item").Name)
+		require.Empty(t, model.Struct("Wasm").Name, "build constraints are read from the FS")
+
+		infos, err = ParseFS(fsys, "model", ParseOptions{GOOS: "js", GOARCH: "wasm"})
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		require.Len(t, infos[0].Packages[0].Structs, 2)
+	})
+
+	t.Run("FSOverlay", func(t *testing.T) {
+		infos, err := ParseFS(fsys, ".", ParseOptions{Overlay: map[string][]byte{
+			"cart.go":      []byte("package shop\n\ntype Cart struct {\n\tItems []string\n\tTotal int\n}\n"),
+			"/cart_add.go": []byte("package shop\n\nfunc (c *Cart) Add(item string) {}\n"),
+		}})
+		require.NoError(t, err)
+		require.Len(t, infos, 2)
+		cart := newHelper(&infos[0].Packages[0]).Struct("Cart")
+		require.Len(t, cart.Fields, 2)
+		require.Len(t, cart.Methods, 1)
+		require.Equal(t, "cart_add.go", cart.Methods[0].Position.File)
+	})
+
+	t.Run("FSOverlayNewPackage", func(t *testing.T) {
+		infos, err := ParseFS(fsys, ".", ParseOptions{Overlay: map[string][]byte{
+			"billing/invoice/invoice.go": []byte("package invoice\n\ntype Invoice struct{}\n"),
+		}})
+		require.NoError(t, err)
+		require.Len(t, infos, 3)
+		require.Equal(t, "example.com/shop/billing/invoice", infos[1].Modules[0].FullName)
+		require.Equal(t, "Invoice", infos[1].Packages[0].Structs[0].Name)
+	})
+
+	t.Run("NoModule", func(t *testing.T) {
+		infos, err := ParseFS(fstest.MapFS{"a/a.go": {Data: []byte("package a\n\nfunc A() {}\n")}}, "", ParseOptions{})
+		require.NoError(t, err)
+		require.Len(t, infos, 1)
+		require.Equal(t, "", infos[0].Modules[0].RootModuleName)
+		require.Equal(t, "A", infos[0].Packages[0].Functions[0].Name)
+	})
+
+	t.Run("DiskOverlay", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/shop\n\ngo 1.21\n"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "cart.go"), []byte("package shop\n\ntype Cart struct{}\n"), 0644))
+		overlay := map[string][]byte{
+			filepath.Join(dir, "cart.go"):  []byte("package shop\n\ntype Cart struct {\n\tItems []string\n}\n"),
+			filepath.Join(dir, "order.go"): []byte("package shop\n\ntype Order struct {\n\tCart Cart\n}\n"),
+		}
+
+		info, err := ParseDirectoryWithOptions(dir, ParseOptions{AllFiles: true, Overlay: overlay})
+		require.NoError(t, err)
+		h := newHelper(&info.Packages[0])
+		require.Len(t, h.Struct("Cart").Fields, 1)
+		require.Equal(t, "Order", h.Struct("Order").Name)
+
+		infos, err := ParseModules(context.Background(), dir, ParseOptions{AllFiles: true, Overlay: overlay})
+		require.NoError(t, err)
+		require.Len(t, infos[0].Packages[0].Structs, 2)
+
+		overlay[filepath.Join(dir, "billing", "invoice.go")] = []byte("package billing\n\ntype Invoice struct{}\n")
+		infos, err = ParseModules(context.Background(), dir, ParseOptions{AllFiles: true, Overlay: overlay})
+		require.NoError(t, err)
+		require.Len(t, infos[0].Packages, 2, "directories only holding files of the overlay are parsed")
+		require.Equal(t, "billing", infos[0].Packages[1].Package)
+		require.NoDirExists(t, filepath.Join(dir, "billing"))
+
+		content, err := os.ReadFile(filepath.Join(dir, "cart.go"))
+		require.NoError(t, err)
+		require.Equal(t, "package shop\n\ntype Cart struct{}\n", string(content), "files on disk are left untouched")
+	})
+}
+
+func TestParseStructTags(t *testing.T) {
+	code := "package test\n\n" +
+		"type User struct {\n" +
+		"\tID    int    `json:\"id\" db:\"user_id,pk\"`\n" +
+		"\tEmail string `json:\"email,omitempty\" validate:\"required,email\"`\n" +
+		"\tName  string \"json:\\\"name\\\"\"\n" +
+		"\tNote  string `json:\"note\" bad`\n" +
+		"\tAge   int\n" +
+		"}\n"
+	output, err := ParseString(code)
+	require.NoError(t, err)
+	user := newHelper(&output.Packages[0]).Struct("User")
+
+	id := user.fields["ID"]
+	require.Equal(t, `json:"id" db:"user_id,pk"`, id.Tag)
+	require.Equal(t, []Tag{
+		{Key: "json", Value: "id", Name: "id"},
+		{Key: "db", Value: "user_id,pk", Name: "user_id", Options: []string{"pk"}},
+	}, id.Tags)
+
+	email, ok := user.fields["Email"].LookupTag("validate")
+	require.True(t, ok)
+	require.Equal(t, "required", email.Name)
+	require.Equal(t, []string{"email"}, email.Options)
+	_, ok = user.fields["Email"].LookupTag("db")
+	require.False(t, ok)
+
+	require.Equal(t, []Tag{{Key: "json", Value: "name", Name: "name"}}, user.fields["Name"].Tags, "double-quoted tags are unquoted")
+	require.Equal(t, []Tag{{Key: "json", Value: "note", Name: "note"}}, user.fields["Note"].Tags, "parsing stops at malformed keys")
+	require.Empty(t, user.fields["Age"].Tags)
+
+	llm := PrettyPrint([]*ParsedInfo{output}, "llm", nil, true, true, true, true, true, true, true, false, "json")
+	require.Contains(t, llm, `ID (int, json:"id"), Email (string, json:"email,omitempty")`)
+	require.Contains(t, llm, "Age (int)")
+	require.NotContains(t, llm, "db:")
+
+	llm = PrettyPrint([]*ParsedInfo{output}, "llm", nil, true, true, true, true, true, true, true, false)
+	require.Contains(t, llm, "ID (int), Email (string)", "the llm format prints no tags without tag keys")
+	llm = PrettyPrint([]*ParsedInfo{output}, "llm", nil, true, true, true, true, true, true, true, false, AllTagKeys)
+	require.Contains(t, llm, `ID (int, json:"id" db:"user_id,pk")`)
+
+	long := PrettyPrint([]*ParsedInfo{output}, "text_long", nil, true, true, true, true, true, true, true, false, "db")
+	require.Contains(t, long, "    Field: ID int\n      Tag: db:\"user_id,pk\"\n")
+	require.Contains(t, long, "    Field: Email string\n    Field: Name string\n")
+
+	long = PrettyPrint([]*ParsedInfo{output}, "text_long", nil, true, true, true, true, true, true, true, false)
+	require.Contains(t, long, "      Tag: json:\"email,omitempty\" validate:\"required,email\"\n")
+}
+
+func TestParseInlineTypes(t *testing.T) {
+	code := `package test
+
+import "io"
+
+const N = 2
+
+type Server struct {
+	Config struct {
+		Port  int ` + "`json:\"port\"`" + `
+		Hosts []string
+	}
+	Routes  []struct{ Path, Method string }
+	Headers map[string]*struct{ Values []string }
+	Events  <-chan *Event
+	Matrix  [4][N]float64
+	Handle  func(w io.Writer, names ...string) (int, error)
+	X, Y    int
+}
+
+type Event struct{}
+`
+	output, err := ParseString(code)
+	require.NoError(t, err)
+	server := newHelper(&output.Packages[0]).Struct("Server")
+
+	coords := server.fields["X"]
+	require.Equal(t, []string{"X", "Y"}, coords.Names, "fields declaring several names are kept whole")
+	require.Empty(t, server.fields["Y"].Name)
+
+	config := server.fields["Config"].TypeDetails
+	require.True(t, config.IsStruct)
+	require.Len(t, config.Fields, 2)
+	require.Equal(t, "Port", config.Fields[0].Name)
+	require.Equal(t, "int", config.Fields[0].Type)
+	require.True(t, config.Fields[0].TypeDetails.IsBuiltin)
+	require.Equal(t, "port", config.Fields[0].Tags[0].Name)
+	require.False(t, config.Fields[0].Position.IsValid(), "inline fields have no position")
+	require.True(t, config.Fields[1].TypeDetails.IsSlice)
+	require.Equal(t, "string", config.Fields[1].TypeDetails.Elem.TypeName)
+
+	routes := server.fields["Routes"].TypeDetails
+	require.True(t, routes.IsSlice)
+	require.True(t, routes.Elem.IsStruct)
+	require.Equal(t, []string{"Path", "Method"}, []string{routes.Elem.Fields[0].Name, routes.Elem.Fields[1].Name})
+
+	headers := server.fields["Headers"].TypeDetails
+	require.True(t, headers.IsMap)
+	require.Equal(t, "string", headers.Key.TypeName)
+	require.True(t, headers.Elem.IsPointer)
+	require.True(t, headers.Elem.Elem.IsStruct)
+	require.Equal(t, "Values", headers.Elem.Elem.Fields[0].Name)
+
+	events := server.fields["Events"].TypeDetails
+	require.True(t, events.IsChan)
+	require.Equal(t, "recv", events.ChanDir)
+	require.Equal(t, "*Event", events.Elem.TypeName)
+	require.Equal(t, "Event", events.Elem.Elem.TypeName)
+
+	matrix := server.fields["Matrix"]
+	require.Equal(t, "[4][N]float64", matrix.Type)
+	require.True(t, matrix.Slice)
+	require.Equal(t, "[4][N]float64", matrix.TypeDetails.TypeName)
+	require.True(t, matrix.TypeDetails.IsArray)
+	require.Equal(t, "4", matrix.TypeDetails.ArrayLen)
+	require.Equal(t, "N", matrix.TypeDetails.Elem.ArrayLen)
+	require.Equal(t, "float64", matrix.TypeDetails.Elem.Elem.TypeName)
+
+	handle := server.fields["Handle"].TypeDetails
+	require.True(t, handle.IsFunc)
+	require.Len(t, handle.Params, 2)
+	require.Equal(t, "w", handle.Params[0].Name)
+	require.Equal(t, "io.Writer", handle.Params[0].Type)
+	require.Equal(t, "...string", handle.Params[1].Type)
+	require.Equal(t, "string", handle.Params[1].TypeDetails.Elem.TypeName)
+	require.Equal(t, []string{"int", "error"}, []string{handle.Results[0].Type, handle.Results[1].Type})
+}
+
+func TestParseDirectives(t *testing.T) {
+	code := `//go:build linux
+
+//go:generate stringer -type=Status
+package test
+
+import _ "embed"
+
+//go:embed schema.sql
+var schema string
+
+// Status of an order.
+//
+//nolint:revive // names are part of the API
+type Status int
+
+// Order is an order.
+//
+// Deprecated: Use Purchase
+// instead.
+type Order struct {
+	ID   int //nolint:lll
+	// Old is the previous ID.
+	//
+	// Deprecated: Use ID.
+	Old  int
+}
+
+// Add adds two numbers.
+//
+//export Add
+func Add(a, b int) int { return a + b }
+`
+	output, err := ParseString(code)
+	require.NoError(t, err)
+	pkg := &output.Packages[0]
+	h := newHelper(pkg)
+
+	require.Len(t, pkg.Files, 1)
+	require.Equal(t, []string{"go:build", "go:generate", "go:embed", "nolint", "nolint", "export"}, directiveNames(pkg.Files[0].Directives))
+	require.Equal(t, "stringer -type=Status", pkg.Files[0].Directives[1].Args)
+	require.Equal(t, 3, pkg.Files[0].Directives[1].Position.Line)
+
+	schema := h.Variable("schema")
+	require.Equal(t, []Directive{{Name: "go:embed", Args: "schema.sql", Position: schema.Directives[0].Position}}, schema.Directives)
+	require.Empty(t, schema.Docs)
+
+	var status TypeDecl
+	for _, td := range pkg.Types {
+		if td.Name == "Status" {
+			status = td
+		}
+	}
+	require.Equal(t, []string{"nolint"}, directiveNames(status.Directives))
+	require.Equal(t, "revive // names are part of the API", status.Directives[0].Args)
+	require.Equal(t, []string{"Status of an order."}, status.Docs)
+	require.False(t, status.Deprecated)
+
+	order := h.Struct("Order")
+	require.True(t, order.Deprecated)
+	require.Equal(t, "Use Purchase instead.", order.DeprecatedMessage)
+	require.Equal(t, []string{"nolint"}, directiveNames(order.fields["ID"].Directives))
+	require.Equal(t, "lll", order.fields["ID"].Directives[0].Args)
+	require.Empty(t, order.fields["ID"].Comment)
+	require.True(t, order.fields["Old"].Deprecated)
+	require.Equal(t, "Use ID.", order.fields["Old"].DeprecatedMessage)
+
+	add := h.Function("Add")
+	require.Equal(t, []string{"export"}, directiveNames(add.Directives))
+	require.Equal(t, []string{"Add adds two numbers."}, add.Docs)
+	require.False(t, add.Deprecated)
+}
+
+func directiveNames(directives []Directive) []string {
+	names := make([]string, 0, len(directives))
+	for _, d := range directives {
+		names = append(names, d.Name)
+	}
+	return names
+}
+
+func TestParsePackageDocsAndFileHeaders(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+	write("go.mod", "module example.com/shop\n\ngo 1.21\n")
+	write("shop.go", `// Copyright 2024 The Shop Authors.
+// Licensed under the MIT license.
+
+//go:build !windows
+
+// Package shop sells things. It also ships them.
+//
+// Orders are paid upfront.
+package shop
+
+import (
+	"fmt"
+	str "strings"
+)
+
+func Buy() string { return fmt.Sprint(str.ToUpper("x")) }
+`)
+	write("cart.go", "package shop\n\nimport \"sort\"\n\nvar _ = sort.Strings\n")
+	write("shop_test.go", "package shop_test\n\nfunc TestBuy() {}\n")
+
+	cache, err := OpenParseCache(t.TempDir())
+	require.NoError(t, err)
+	for _, opts := range []ParseOptions{{Tests: true}, {Tests: true, Cache: cache}} {
+		parsed, err := ParseDirectoryWithOptions(dir, opts)
+		require.NoError(t, err)
+		packages := map[string]Package{}
+		for _, pkg := range parsed.Packages {
+			packages[pkg.Package] = pkg
+		}
+
+		shop := packages["shop"]
+		require.Equal(t, "example.com/shop", shop.ImportPath)
+		require.Equal(t, []string{"Package shop sells things. It also ships them.", "Orders are paid upfront."}, shop.Docs)
+		require.Equal(t, "Package shop sells things.", shop.Synopsis())
+		require.Equal(t, "example.com/shop_test", packages["shop_test"].ImportPath)
+		require.Empty(t, packages["shop_test"].Docs)
+
+		file, ok := shop.File(filepath.Join(dir, "shop.go"))
+		require.True(t, ok)
+		require.Equal(t, "Copyright 2024 The Shop Authors.\nLicensed under the MIT license.", file.Header)
+		require.Equal(t, []Import{{Path: "fmt"}, {Name: "str", Path: "strings"}}, file.Imports)
+
+		file, ok = shop.File(filepath.Join(dir, "cart.go"))
+		require.True(t, ok)
+		require.Empty(t, file.Header)
+		require.Equal(t, []Import{{Path: "sort"}}, file.Imports)
+
+		llm := PrettyPrint([]*ParsedInfo{parsed}, "llm", nil, true, true, true, true, true, true, false, true)
+		require.Contains(t, llm, "Package: shop\n  Synopsis: Package shop sells things.\n")
+		llm = PrettyPrint([]*ParsedInfo{parsed}, "llm", nil, true, true, true, true, true, true, false, false)
+		require.NotContains(t, llm, "Synopsis:")
+	}
+}
+
+func TestPrettyPrintJSONL(t *testing.T) {
+	code := `package test
+
+type Cart struct {
+	Items []string
+}
+
+func (c *Cart) Add(item string) {}
+
+type Status int
+
+func (s Status) String() string { return "" }
+
+func New() *Cart { return nil }
+
+var Default = New()
+
+const Limit = 10
+`
+	output, err := ParseString(code)
+	require.NoError(t, err)
+
+	out := PrettyPrint([]*ParsedInfo{output}, "jsonl", nil, true, true, true, true, true, true, true, true)
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+
+	var kinds, names []string
+	for _, line := range lines {
+		var record struct {
+			Record
+			Payload json.RawMessage `json:"payload"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		require.Equal(t, "test", record.Package)
+		kinds = append(kinds, record.Kind)
+		names = append(names, record.Name)
+		if record.Kind == RecordPackage {
+			require.Nil(t, record.Position)
+			continue
+		}
+		require.NotNil(t, record.Position)
+		require.Positive(t, record.Position.Line)
+		if record.Kind == RecordStruct {
+			var s Struct
+			require.NoError(t, json.Unmarshal(record.Payload, &s))
+			require.Len(t, s.Fields, 1)
+			require.Empty(t, s.Methods, "methods have their own records")
+		}
+	}
+	require.Equal(t, []string{RecordPackage, RecordStruct, RecordMethod, RecordType, RecordMethod, RecordFunction, RecordVariable, RecordConstant}, kinds)
+	require.Equal(t, []string{"test", "Cart", "Cart.Add", "Status", "Status.String", "New", "Default", "Limit"}, names)
+}
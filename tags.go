@@ -0,0 +1,116 @@
+package codesurgeon
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Tag is a key of a struct field tag, e.g. json:"name,omitempty".
+type Tag struct {
+	Key     string   `json:"key"`               // e.g. "json"
+	Value   string   `json:"value"`             // Unquoted value, e.g. "name,omitempty"
+	Name    string   `json:"name"`              // Value up to the first comma, e.g. "name"
+	Options []string `json:"options,omitempty"` // Values after the first comma, e.g. ["omitempty"]
+}
+
+// String returns the tag formatted as in the source, e.g. json:"name,omitempty".
+func (t Tag) String() string {
+	return t.Key + ":" + strconv.Quote(t.Value)
+}
+
+// LookupTag returns the tag of f with the given key, like reflect.StructTag.Lookup.
+func (f Field) LookupTag(key string) (Tag, bool) {
+	for _, t := range f.Tags {
+		if t.Key == key {
+			return t, true
+		}
+	}
+	return Tag{}, false
+}
+
+// SetTag sets the value of the key tag of f, adding the key after the others when f has none,
+// and updates Tag accordingly, e.g. f.SetTag("json", "name,omitempty").
+func (f *Field) SetTag(key, value string) {
+	name, options, _ := strings.Cut(value, ",")
+	tag := Tag{Key: key, Value: value, Name: name}
+	if options != "" {
+		tag.Options = strings.Split(options, ",")
+	}
+	i := slices.IndexFunc(f.Tags, func(t Tag) bool { return t.Key == key })
+	if i >= 0 {
+		f.Tags[i] = tag
+	} else {
+		f.Tags = append(f.Tags, tag)
+	}
+	f.Tag = formatTags(f.Tags, nil)
+}
+
+// parseStructTag parses a struct tag, without its quotes, into its keys in the order they're written.
+// Like reflect.StructTag.Lookup, parsing stops at the first malformed key.
+func parseStructTag(tag string) []Tag {
+	var tags []Tag
+	for tag != "" {
+		// Skip leading space
+		i := 0
+		for i < len(tag) && tag[i] == ' ' {
+			i++
+		}
+		tag = tag[i:]
+		if tag == "" {
+			break
+		}
+
+		// Scan to colon. A space, a quote or a control character is a syntax error.
+		i = 0
+		for i < len(tag) && tag[i] > ' ' && tag[i] != ':' && tag[i] != '"' && tag[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(tag) || tag[i] != ':' || tag[i+1] != '"' {
+			break
+		}
+		key := tag[:i]
+		tag = tag[i+1:]
+
+		// Scan quoted string to find value
+		i = 1
+		for i < len(tag) && tag[i] != '"' {
+			if tag[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(tag) {
+			break
+		}
+		value, err := strconv.Unquote(tag[:i+1])
+		if err != nil {
+			break
+		}
+		tag = tag[i+1:]
+
+		name, options, _ := strings.Cut(value, ",")
+		t := Tag{Key: key, Value: value, Name: name}
+		if options != "" {
+			t.Options = strings.Split(options, ",")
+		}
+		tags = append(tags, t)
+	}
+	return tags
+}
+
+// AllTagKeys selects every struct tag key in the tag keys given to PrettyPrint.
+const AllTagKeys = "*"
+
+// formatTags returns tags formatted as in the source, keeping only the given keys when there are any.
+func formatTags(tags []Tag, keys []string) string {
+	all := len(keys) == 0 || slices.Contains(keys, AllTagKeys)
+	var parts []string
+	for _, t := range tags {
+		if !all && !slices.Contains(keys, t.Key) {
+			continue
+		}
+		parts = append(parts, t.String())
+	}
+	return strings.Join(parts, " ")
+}